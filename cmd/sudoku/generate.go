@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sudoku_gen_go/db"
+	"sudoku_gen_go/internal/generator"
+	"sudoku_gen_go/internal/grader"
+	"sudoku_gen_go/internal/types"
+	"time"
+)
+
+func runGenerate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	size := fs.Int("size", 9, fmt.Sprintf("grid size from %d to %d", minSize, maxSize))
+	box := fs.String("box", "", `box shape as "WxH", e.g. "2x3" (default depends on size)`)
+	layout := fs.String("layout", "normal", "layout type: normal or jigsaw")
+	variant := fs.String("variant", "", "variant rules on top of the layout: x, hyper or killer, comma-separated to combine")
+	difficulty := fs.Int("difficulty", 3, "difficulty from 1 to 5")
+	band := fs.String("band", "", `target technique band as "min..max", e.g. "hidden pair..xy-wing"`)
+	clues := fs.String("clues", "", `number of givens, exact ("24") or a range ("17..24")`)
+	cageSize := fs.String("cage-size", "", `cells per killer cage, exact ("3") or a range ("2..5")`)
+	minimal := fs.Bool("minimal", false, "generate minimal puzzles, where every clue is needed for a unique solution")
+	symmetry := fs.String("symmetry", "none", "clue pattern: none, rotational180, rotational90, horizontal, vertical or diagonal")
+	count := fs.Int("count", 1, "number of puzzles to generate")
+	threads := fs.Int("threads", min(runtime.NumCPU(), 32), "worker threads per puzzle, 1 to 32")
+	seed := fs.Int64("seed", 0, "seed for reproducible output; each puzzle tried uses seed, seed+1, ...")
+	timeout := fs.Duration("timeout", 0, "give up on a puzzle after this long (default depends on size and layout)")
+	format := fs.String("format", "terminal", "output format: terminal, text or json")
+	out := fs.String("out", "", "directory to save generated puzzles to as JSON files")
+	upload := fs.Bool("upload", false, "upload generated puzzles to the store chosen with -store")
+	storage := addStoreFlags(fs)
+	quiet := fs.Bool("quiet", false, "do not print generation progress")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Ask only for what the command line left out
+	set := setFlags(fs)
+	reader := bufio.NewReader(os.Stdin)
+	prompts := []struct {
+		name, prompt string
+		validator    func(string) bool
+	}{
+		{"size", fmt.Sprintf("Enter grid size (%d-%d): ", minSize, maxSize), validateSize},
+		{"layout", "Enter layout type (normal/jigsaw): ", validateLayout},
+		{"difficulty", "Enter difficulty (1-5): ", validateDifficulty},
+		{"count", "How many puzzles to generate: ", validateCount},
+		{"threads", "Enter number of threads (1-32): ", validateThreads},
+		{"upload", "Upload puzzles? (yes/no): ", validateYesNo},
+	}
+	for _, p := range prompts {
+		if p.name == "difficulty" && set["band"] {
+			continue
+		}
+		if err := promptMissing(fs, set, reader, p.name, p.prompt, p.validator); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case !validateSize(strconv.Itoa(*size)):
+		return fmt.Errorf("invalid size %d: must be between %d and %d", *size, minSize, maxSize)
+	case !validateLayout(*layout):
+		return fmt.Errorf("invalid layout %q: must be normal or jigsaw", *layout)
+	case !validateDifficulty(strconv.Itoa(*difficulty)):
+		return fmt.Errorf("invalid difficulty %d: must be between 1 and 5", *difficulty)
+	case set["difficulty"] && set["band"]:
+		return errors.New("-difficulty and -band cannot be combined: with -band the stored difficulty follows the grade")
+	case *count < 1:
+		return fmt.Errorf("invalid count %d: must be at least 1", *count)
+	case !validateThreads(strconv.Itoa(*threads)):
+		return fmt.Errorf("invalid threads %d: must be between 1 and 32", *threads)
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	clueSymmetry, err := generator.ParseSymmetry(*symmetry)
+	if err != nil {
+		return err
+	}
+	variants, err := types.ParseVariants(*variant)
+	if err != nil {
+		return err
+	}
+	minClues, maxClues, err := parseRange(*clues, "clue count")
+	if err != nil {
+		return err
+	}
+	minCage, maxCage, err := parseRange(*cageSize, "cage size")
+	if err != nil {
+		return err
+	}
+	var target *grader.Band
+	if *band != "" {
+		b, err := grader.ParseBand(*band)
+		if err != nil {
+			return err
+		}
+		target = &b
+	}
+
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			return err
+		}
+	}
+	var store db.Store
+	if *upload {
+		s, err := storage.open()
+		if err != nil {
+			return err
+		}
+		if c, ok := s.(io.Closer); ok {
+			defer c.Close()
+		}
+		store = s
+	}
+
+	sudokuType := types.Normal
+	if *layout == "jigsaw" {
+		sudokuType = types.Jigsaw
+	}
+	limit := *timeout
+	if limit <= 0 {
+		limit = generationTimeout(*size, sudokuType)
+	}
+
+	// One generator for the whole run, so it never repeats an isomorph
+	gen := generator.NewClassicGenerator(*size, sudokuType)
+	if *box != "" {
+		boxWidth, boxHeight, err := parseBox(*box)
+		if err != nil {
+			return err
+		}
+		if err := gen.SetBoxDimensions(boxWidth, boxHeight); err != nil {
+			return err
+		}
+	} else if _, _, err := types.BoxDimensions(*size); err != nil && sudokuType != types.Jigsaw {
+		return fmt.Errorf("%v: use -layout jigsaw", err)
+	}
+	if err := gen.SetVariants(variants...); err != nil {
+		return err
+	}
+	gen.SetDifficulty(*difficulty)
+	gen.SetThreads(*threads)
+	gen.SetSymmetry(clueSymmetry)
+	gen.SetMinimal(*minimal)
+	if maxClues > 0 {
+		if err := gen.SetClueBounds(minClues, maxClues); err != nil {
+			return err
+		}
+	}
+	if maxCage > 0 {
+		if err := gen.SetCageSizes(minCage, maxCage); err != nil {
+			return err
+		}
+	}
+	if target != nil {
+		gen.SetTargetDifficulty(*target)
+	}
+	if err := gen.Check(); err != nil {
+		return err
+	}
+	if !*quiet {
+		gen.SetProgress(printEvent)
+	}
+	if store != nil {
+		gen.SetKnown(func(fingerprint string) bool {
+			_, err := store.GetByFingerprint(fingerprint)
+			return err == nil
+		})
+	}
+
+	tries, failures := 0, 0
+	successfulPuzzles := 0
+	for successfulPuzzles < *count && ctx.Err() == nil {
+		logf(*quiet, "\nGenerating puzzle %d/%d\n", successfulPuzzles+1, *count)
+		level := fmt.Sprintf("Difficulty: %d", *difficulty)
+		if target != nil {
+			level = "Band: " + target.String()
+		}
+		logf(*quiet, "Generating %v Sudoku %dx%d (%s)\n",
+			typeName(sudokuType, variants), *size, *size, level)
+
+		start := time.Now()
+		if set["seed"] {
+			gen.SetSeed(*seed + int64(tries))
+		}
+		tries++
+
+		genCtx, cancel := context.WithTimeout(ctx, limit)
+		grid, err := gen.GenerateContext(genCtx)
+		cancel()
+		logf(*quiet, "Generation time: %v\n", time.Since(start))
+
+		if errors.Is(err, generator.ErrClueBounds) {
+			// More tries would only repeat the same retry budget
+			return err
+		}
+		if err != nil {
+			failures++
+			if failures == maxFailures {
+				return fmt.Errorf("giving up after %d failed puzzles in a row: %w", failures, err)
+			}
+			fmt.Fprintf(os.Stderr, "Error generating puzzle: %v\n", err)
+			continue
+		}
+		failures = 0
+
+		if err := renderGrid(grid, *format); err != nil {
+			return err
+		}
+
+		if *out != "" {
+			path, err := saveGrid(*out, grid)
+			if err != nil {
+				return err
+			}
+			logf(*quiet, "💾 Saved puzzle to %s\n", path)
+		}
+
+		if *upload {
+			logf(*quiet, "\nUploading puzzle to %s store...\n", *storage.kind)
+			level := *difficulty
+			if target != nil {
+				// The difficulty flag did not steer generation, the band did
+				result, err := grader.Grade(grid)
+				if err != nil {
+					return err
+				}
+				level = result.Hardest.Level()
+			}
+			rec, err := uploadGrid(store, grid, level)
+			if err != nil {
+				return fmt.Errorf("uploading puzzle: %w", err)
+			}
+			logf(*quiet, "✅ Successfully uploaded sudoku with ID: %s (code %s)\n", rec.ID, rec.Code)
+		}
+		successfulPuzzles++
+	}
+
+	if successfulPuzzles < *count {
+		return fmt.Errorf("generated %d of %d puzzles: %w", successfulPuzzles, *count, ctx.Err())
+	}
+	return nil
+}
+
+// parseBox reads the -box flag, "WxH"
+func parseBox(s string) (width, height int, err error) {
+	w, h, found := strings.Cut(strings.ToLower(s), "x")
+	if !found {
+		return 0, 0, fmt.Errorf(`invalid box %q: want "WxH"`, s)
+	}
+	if width, err = strconv.Atoi(w); err != nil {
+		return 0, 0, fmt.Errorf(`invalid box %q: want "WxH"`, s)
+	}
+	if height, err = strconv.Atoi(h); err != nil {
+		return 0, 0, fmt.Errorf(`invalid box %q: want "WxH"`, s)
+	}
+	return width, height, nil
+}
+
+// parseRange reads the -clues and -cage-size flags, "n" or "min..max";
+// an empty flag gives 0, 0 for no bounds. what names the flag's value in
+// errors.
+func parseRange(s, what string) (lo, hi int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	from, to, found := strings.Cut(s, "..")
+	if !found {
+		to = from
+	}
+	if lo, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return 0, 0, fmt.Errorf("invalid %s %q", what, s)
+	}
+	if hi, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+		return 0, 0, fmt.Errorf("invalid %s %q", what, s)
+	}
+	return lo, hi, nil
+}
+
+// typeName names a layout together with its variants, such as "jigsaw+x"
+func typeName(typ types.SudokuType, variants []types.SudokuType) string {
+	name := string(typ)
+	for _, v := range variants {
+		name += "+" + string(v)
+	}
+	return name
+}
+
+// saveGrid writes grid as JSON into dir, named after its size, layout,
+// variants and seed so regenerating the same puzzle overwrites the same
+// file
+func saveGrid(dir string, grid *types.Grid) (string, error) {
+	data, err := grid.ToJSON()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%dx%d-%s-%d.json", grid.Size, grid.Size, typeName(grid.Type, grid.Variants), grid.Seed)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// logf prints progress to stderr so stdout carries only the puzzles
+func logf(quiet bool, format string, args ...any) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// printEvent renders generator progress on the terminal
+func printEvent(e generator.Event) {
+	switch e.Kind {
+	case generator.AttemptStarted:
+		fmt.Fprintf(os.Stderr, "Thread %d: Attempt %d/%d\n", e.Worker, e.Attempt+1, e.Attempts)
+	case generator.RegionsProgress:
+		fmt.Fprintf(os.Stderr, "Generating jigsaw regions... %d%%\n", e.Percent)
+	case generator.AttemptFailed:
+		if !errors.Is(e.Err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "Thread %d: Attempt %d failed: %v\n", e.Worker, e.Attempt+1, e.Err)
+		}
+	case generator.Generated:
+		fmt.Fprintf(os.Stderr, "Successfully generated puzzle with %d clues\n", e.Clues)
+	}
+}
+
+// Grid sizes the command accepts; the solver could go larger, but
+// generation gets slow past 25x25
+const (
+	minSize = 4
+	maxSize = 25
+)
+
+// maxFailures is how many puzzles in a row may fail to generate before the
+// command gives up, so unattended runs with hopeless settings end
+const maxFailures = 5
+
+// generationTimeout is how long a single puzzle may take before giving up
+func generationTimeout(size int, sudokuType types.SudokuType) time.Duration {
+	if sudokuType == types.Jigsaw {
+		return max(15*time.Second, time.Duration(size*size)*50*time.Millisecond)
+	}
+	return max(5*time.Second, time.Duration(size*size)*20*time.Millisecond)
+}
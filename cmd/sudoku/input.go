@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sudoku_gen_go/internal/types"
+)
+
+// puzzle is a grid read from an input, named for use in messages
+type puzzle struct {
+	name string
+	grid *types.Grid
+}
+
+// readPuzzles decodes every grid in the given files, which may each hold a
+// stream of JSON grids. No paths or "-" reads stdin.
+func readPuzzles(paths []string) ([]puzzle, error) {
+	if len(paths) == 0 {
+		if isTerminal(os.Stdin) {
+			return nil, errors.New("no input: pass puzzle files or pipe JSON on stdin")
+		}
+		paths = []string{"-"}
+	}
+
+	var puzzles []puzzle
+	for _, path := range paths {
+		read, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		puzzles = append(puzzles, read...)
+	}
+	return puzzles, nil
+}
+
+func readFile(path string) ([]puzzle, error) {
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r, name = f, path
+	}
+
+	var grids []*types.Grid
+	dec := json.NewDecoder(r)
+	for {
+		var grid types.Grid
+		err := dec.Decode(&grid)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		grids = append(grids, &grid)
+	}
+
+	puzzles := make([]puzzle, len(grids))
+	for i, grid := range grids {
+		puzzles[i] = puzzle{name: name, grid: grid}
+		if len(grids) > 1 {
+			puzzles[i].name = fmt.Sprintf("%s#%d", name, i+1)
+		}
+	}
+	return puzzles, nil
+}
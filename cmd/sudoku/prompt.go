@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// isTerminal reports whether f is attached to an interactive terminal.
+// /dev/null is a character device too, which is what cron jobs usually
+// get as stdin, so it is ruled out explicitly.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// setFlags returns the names of the flags given on the command line
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// promptMissing asks for the value of flag name on the terminal unless it
+// was given on the command line or stdin is not a terminal
+func promptMissing(fs *flag.FlagSet, set map[string]bool, reader *bufio.Reader, name, prompt string, validator func(string) bool) error {
+	if set[name] || !isTerminal(os.Stdin) {
+		return nil
+	}
+	input, err := getUserInput(reader, prompt, validator)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	// Boolean flags are answered with yes or no
+	switch input {
+	case "y", "yes":
+		input = "true"
+	case "n", "no":
+		input = "false"
+	}
+	return fs.Set(name, input)
+}
+
+func getUserInput(reader *bufio.Reader, prompt string, validator func(string) bool) (string, error) {
+	for {
+		fmt.Print(prompt)
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		if validator(input) {
+			return input, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Println("Invalid input, please try again.")
+	}
+}
+
+func validateSize(input string) bool {
+	size, err := strconv.Atoi(input)
+	return err == nil && size >= minSize && size <= maxSize
+}
+
+func validateLayout(input string) bool {
+	return input == "normal" || input == "jigsaw"
+}
+
+func validateDifficulty(input string) bool {
+	diff, err := strconv.Atoi(input)
+	return err == nil && diff >= 1 && diff <= 5
+}
+
+func validateCount(input string) bool {
+	count, err := strconv.Atoi(input)
+	return err == nil && count > 0 && count <= 100
+}
+
+// Add new validator
+func validateThreads(input string) bool {
+	threads, err := strconv.Atoi(input)
+	return err == nil && threads >= 1 && threads <= 32
+}
+
+func validateYesNo(input string) bool {
+	return input == "y" || input == "yes" || input == "n" || input == "no"
+}
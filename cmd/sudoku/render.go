@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sudoku_gen_go/internal/types"
+	"sudoku_gen_go/internal/visualizer"
+)
+
+func runRender(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	format := fs.String("format", "terminal", "output format: terminal or text")
+	solution := fs.Bool("solution", false, "render the stored solution instead of the puzzle")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "terminal" && *format != "text" {
+		return fmt.Errorf("unknown render format %q", *format)
+	}
+
+	puzzles, err := readPuzzles(fs.Args())
+	if err != nil {
+		return err
+	}
+	for i, p := range puzzles {
+		grid := p.grid
+		if *solution {
+			if len(grid.Solution) != grid.Size {
+				return fmt.Errorf("%s: no stored solution", p.name)
+			}
+			grid = withPuzzle(grid, grid.Solution)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := renderGrid(grid, *format); err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+	}
+	return nil
+}
+
+// renderGrid writes grid to stdout as terminal, text or json
+func renderGrid(grid *types.Grid, format string) error {
+	if format == "json" {
+		return json.NewEncoder(os.Stdout).Encode(grid)
+	}
+
+	if len(grid.Puzzle) != grid.Size {
+		return fmt.Errorf("puzzle must have %d rows", grid.Size)
+	}
+	for i, row := range grid.Puzzle {
+		if len(row) != grid.Size {
+			return fmt.Errorf("row %d must have %d cells", i+1, grid.Size)
+		}
+	}
+
+	if grid.Type == types.Jigsaw || len(grid.SubGrids) > 0 {
+		if err := types.CheckRegions(grid.Size, grid.SubGrids); err != nil {
+			return err
+		}
+	}
+	// The visualizer trusts the cages and windows of the variants
+	if err := grid.CheckVariants(); err != nil {
+		return err
+	}
+
+	viz := visualizer.NewVisualizer(grid)
+	switch {
+	case format == "text" && len(grid.Cages) > 0:
+		viz.PrintCagesText()
+	case format == "text":
+		viz.PrintText()
+	case len(grid.Cages) > 0:
+		viz.PrintCages()
+	case grid.Type == types.Jigsaw:
+		viz.PrintJigsaw()
+	default:
+		viz.Print()
+	}
+	return nil
+}
+
+// withPuzzle returns a shallow copy of grid showing cells as its puzzle
+func withPuzzle(grid *types.Grid, cells [][]int) *types.Grid {
+	shown := *grid
+	shown.Puzzle = cells
+	return &shown
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sudoku_gen_go/internal/grader"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+)
+
+func runSolve(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("solve", flag.ContinueOnError)
+	format := fs.String("format", "terminal", "output format: terminal, text or json")
+	backend := fs.String("solver", "backtracking", "solver backend: backtracking or dlx")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	s, err := solver.ByName(*backend)
+	if err != nil {
+		return err
+	}
+
+	puzzles, err := readPuzzles(fs.Args())
+	if err != nil {
+		return err
+	}
+	opts := solver.Options{Stop: func() bool { return ctx.Err() != nil }}
+	for i, p := range puzzles {
+		count, err := s.CountSolutions(p.grid, 2, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%s: %v", p.name, solver.ErrNoSolution)
+		}
+		if count > 1 {
+			fmt.Fprintf(os.Stderr, "⚠️ %s: puzzle has more than one solution, showing one\n", p.name)
+		}
+
+		solution, err := s.Solve(p.grid, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+		p.grid.Solution = solution
+
+		if i > 0 && *format != "json" {
+			fmt.Println()
+		}
+		shown := p.grid
+		if *format != "json" {
+			shown = withPuzzle(p.grid, solution)
+		}
+		if err := renderGrid(shown, *format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runGrade(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("grade", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown grade format %q", *format)
+	}
+
+	puzzles, err := readPuzzles(fs.Args())
+	if err != nil {
+		return err
+	}
+	for i, p := range puzzles {
+		result, err := grader.Grade(p.grid)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+
+		if *format == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+				return err
+			}
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s\n", p.name)
+		fmt.Printf("Hardest technique: %v\n", result.Hardest)
+		fmt.Printf("Score: %d\n", result.Score)
+		for _, t := range grader.Techniques() {
+			if n := result.Steps[t]; n > 0 {
+				fmt.Printf("  %-18s %d\n", t.String()+":", n)
+			}
+		}
+	}
+	return nil
+}
+
+func runValidate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	minimal := fs.Bool("minimal", false, "also require every clue to be needed for a unique solution")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	puzzles, err := readPuzzles(fs.Args())
+	if err != nil {
+		return err
+	}
+	invalid := 0
+	for _, p := range puzzles {
+		if err := validateGrid(p.grid); err != nil {
+			fmt.Printf("❌ %s: %v\n", p.name, err)
+			invalid++
+			continue
+		}
+		if *minimal {
+			redundant, err := solver.RedundantClues(p.grid)
+			if err != nil {
+				return fmt.Errorf("%s: %v", p.name, err)
+			}
+			if len(redundant) > 0 {
+				fmt.Printf("❌ %s: not minimal, %d redundant clues: %s\n", p.name, len(redundant), cellNames(redundant, p.grid.Size))
+				invalid++
+				continue
+			}
+			fmt.Printf("✅ %s: valid, unique solution, minimal\n", p.name)
+			continue
+		}
+		fmt.Printf("✅ %s: valid, unique solution\n", p.name)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d puzzles are invalid", invalid, len(puzzles))
+	}
+	return nil
+}
+
+// validateGrid checks that the grid is well formed, has exactly one
+// solution, and that a stored solution (if any) is that solution
+func validateGrid(grid *types.Grid) error {
+	count, err := solver.CountSolutionsWith(grid, 2, solver.Options{})
+	if err != nil {
+		return err
+	}
+	switch count {
+	case 0:
+		return solver.ErrNoSolution
+	case 1:
+	default:
+		return solver.ErrNotUnique
+	}
+
+	if len(grid.Solution) == 0 {
+		return nil
+	}
+	solution, err := solver.Solve(grid)
+	if err != nil {
+		return err
+	}
+	if len(grid.Solution) != grid.Size {
+		return fmt.Errorf("stored solution must have %d rows", grid.Size)
+	}
+	for i, row := range grid.Solution {
+		if len(row) != grid.Size {
+			return fmt.Errorf("stored solution row %d must have %d cells", i+1, grid.Size)
+		}
+	}
+	if !verifySolution(grid) {
+		return fmt.Errorf("stored solution repeats a digit in a row, column or region")
+	}
+	if err := grid.CheckConstraints(grid.Solution); err != nil {
+		return fmt.Errorf("stored solution breaks a rule: %v", err)
+	}
+	for i := range solution {
+		for j := range solution[i] {
+			if grid.Solution[i][j] != solution[i][j] {
+				return fmt.Errorf("stored solution differs at row %d, column %d", i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFormat rejects output formats renderGrid does not know
+func checkFormat(format string) error {
+	switch format {
+	case "terminal", "text", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func verifySolution(grid *types.Grid) bool {
+	// Verify rows
+	for i := 0; i < grid.Size; i++ {
+		if !isValidSet(grid.Solution[i]) {
+			return false
+		}
+	}
+
+	// Verify columns
+	for i := 0; i < grid.Size; i++ {
+		col := make([]int, grid.Size)
+		for j := 0; j < grid.Size; j++ {
+			col[j] = grid.Solution[j][i]
+		}
+		if !isValidSet(col) {
+			return false
+		}
+	}
+
+	// Verify subgrids
+	for _, region := range grid.SubGrids {
+		values := make([]int, len(region))
+		for i, idx := range region {
+			row, col := idx/grid.Size, idx%grid.Size
+			values[i] = grid.Solution[row][col]
+		}
+		if !isValidSet(values) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isValidSet(nums []int) bool {
+	seen := make(map[int]bool)
+	for _, num := range nums {
+		if num == 0 {
+			continue
+		}
+		if seen[num] {
+			return false
+		}
+		seen[num] = true
+	}
+	return true
+}
+
+// cellNames lists cell indices as r1c1 style names, counting from 1
+func cellNames(cells []int, size int) string {
+	names := make([]string, len(cells))
+	for i, cell := range cells {
+		names[i] = fmt.Sprintf("r%dc%d", cell/size+1, cell%size+1)
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sudoku_gen_go/db"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+)
+
+func runUpload(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	difficulty := fs.Int("difficulty", 0, "difficulty from 1 to 5 recorded with the puzzles")
+	storage := addStoreFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Read the puzzles before prompting so piped input is not consumed
+	// by the prompt
+	puzzles, err := readPuzzles(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := promptMissing(fs, setFlags(fs), bufio.NewReader(os.Stdin), "difficulty", "Enter difficulty (1-5): ", validateDifficulty); err != nil {
+		return err
+	}
+	if !validateDifficulty(strconv.Itoa(*difficulty)) {
+		return fmt.Errorf("invalid difficulty %d: must be between 1 and 5", *difficulty)
+	}
+
+	for _, p := range puzzles {
+		if err := validateGrid(p.grid); err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+		if len(p.grid.Solution) == 0 {
+			solution, err := solver.Solve(p.grid)
+			if err != nil {
+				return fmt.Errorf("%s: %v", p.name, err)
+			}
+			p.grid.Solution = solution
+		}
+	}
+	store, err := storage.open()
+	if err != nil {
+		return err
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	failed := 0
+	for _, p := range puzzles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rec, err := uploadGrid(store, p.grid, *difficulty)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", p.name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s: uploaded with ID %s (code %s)\n", p.name, rec.ID, rec.Code)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d uploads failed", failed, len(puzzles))
+	}
+	return nil
+}
+
+// storeFlags choose where uploaded puzzles are saved
+type storeFlags struct {
+	kind *string
+	path *string
+
+	// PocketBase settings; each overrides the config file and environment
+	config         *string
+	url            *string
+	collection     *string
+	auth           *string
+	authCollection *string
+	email          *string
+}
+
+func addStoreFlags(fs *flag.FlagSet) storeFlags {
+	return storeFlags{
+		kind:           fs.String("store", "pocketbase", "where uploads are saved: pocketbase, file or sqlite"),
+		path:           fs.String("store-path", "", `directory of the file store (default "sudokus") or sqlite database (default "sudokus.db")`),
+		config:         fs.String("pb-config", "", "JSON file with PocketBase settings (url, collection, auth, authCollection, email, password, token)"),
+		url:            fs.String("pb-url", "", "PocketBase URL (default "+db.DefaultURL+")"),
+		collection:     fs.String("pb-collection", "", `PocketBase collection (default "sudokus")`),
+		auth:           fs.String("pb-auth", "", "PocketBase auth mode: superuser, user or token (default superuser)"),
+		authCollection: fs.String("pb-auth-collection", "", `auth collection for user or token auth (default "users" or "_superusers")`),
+		email:          fs.String("pb-email", "", "PocketBase login email; the password comes from POCKETBASE_PASSWORD or the config file"),
+	}
+}
+
+// pocketBaseConfig layers the defaults, the config file, the environment
+// and the command line, each overriding the one before
+func (f storeFlags) pocketBaseConfig() (db.Config, error) {
+	cfg := db.DefaultConfig()
+	if *f.config != "" {
+		if err := cfg.LoadConfigFile(*f.config); err != nil {
+			return cfg, err
+		}
+	}
+	cfg.LoadEnv()
+
+	cfg.URL = cmp.Or(*f.url, cfg.URL)
+	cfg.Collection = cmp.Or(*f.collection, cfg.Collection)
+	cfg.Auth = cmp.Or(*f.auth, cfg.Auth)
+	cfg.AuthCollection = cmp.Or(*f.authCollection, cfg.AuthCollection)
+	cfg.Email = cmp.Or(*f.email, cfg.Email)
+	return cfg, nil
+}
+
+// open returns the chosen store; only the PocketBase store needs the
+// network or credentials
+func (f storeFlags) open() (db.Store, error) {
+	switch *f.kind {
+	case "pocketbase":
+		cfg, err := f.pocketBaseConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Authenticating with PocketBase at %v...\n", cfg)
+		store, err := db.ConnectPocketBase(cfg)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, "✅ Successfully authenticated with PocketBase")
+		return store, nil
+	case "file":
+		return db.NewFileStore(cmp.Or(*f.path, "sudokus"))
+	case "sqlite":
+		return db.OpenSQLite(cmp.Or(*f.path, "sudokus.db"))
+	default:
+		return nil, fmt.Errorf("unknown store %q: must be pocketbase, file or sqlite", *f.kind)
+	}
+}
+
+// uploadGrid saves grid to store and returns the saved record, which
+// carries the code the store gave it
+func uploadGrid(store db.Store, grid *types.Grid, difficulty int) (*db.SudokuRecord, error) {
+	rec, err := db.NewRecord(grid, difficulty)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := store.Save(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func runMigrateIDs(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate-ids", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be migrated")
+	storage := addStoreFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := storage.open()
+	if err != nil {
+		return err
+	}
+	if c, ok := store.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	migrations, err := db.MigrateLegacyIDs(store, *dryRun)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, m := range migrations {
+		switch {
+		case m.Err != nil:
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", m.OldID, m.Err)
+			failed++
+		case m.Duplicate:
+			fmt.Printf("⚠️ %s: already stored as %s, left in place\n", m.OldID, m.NewID)
+		case *dryRun:
+			fmt.Printf("%s → %s\n", m.OldID, m.NewID)
+		default:
+			fmt.Printf("✅ %s → %s (code %s)\n", m.OldID, m.NewID, m.Code)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d legacy records found\n", len(migrations))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d migrations failed", failed, len(migrations))
+	}
+	return nil
+}
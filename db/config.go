@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/habibrosyad/pocketbase-go-sdk"
+	"github.com/joho/godotenv"
+)
+
+// DefaultURL is the PocketBase server the sudoku app reads from
+const DefaultURL = "https://base.mljr.eu"
+
+// Auth modes for Config.Auth
+const (
+	// AuthSuperuser logs in with Email and Password as a superuser
+	AuthSuperuser = "superuser"
+	// AuthUser logs in with Email and Password to a regular auth
+	// collection, "users" unless AuthCollection says otherwise
+	AuthUser = "user"
+	// AuthToken uses an existing Token issued by AuthCollection, which is
+	// "_superusers" unless set to "users"
+	AuthToken = "token"
+)
+
+// Config says which PocketBase server and collection to use and how to
+// log in. The JSON names are the keys of a config file.
+type Config struct {
+	URL            string `json:"url"`
+	Collection     string `json:"collection"`
+	Auth           string `json:"auth"`
+	AuthCollection string `json:"authCollection"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	Token          string `json:"token"`
+}
+
+// DefaultConfig is the production server with superuser login
+func DefaultConfig() Config {
+	return Config{
+		URL:        DefaultURL,
+		Collection: "sudokus",
+		Auth:       AuthSuperuser,
+	}
+}
+
+// envVars maps each config field to the environment variable setting it
+var envVars = []struct {
+	name  string
+	field func(*Config) *string
+}{
+	{"POCKETBASE_URL", func(c *Config) *string { return &c.URL }},
+	{"POCKETBASE_COLLECTION", func(c *Config) *string { return &c.Collection }},
+	{"POCKETBASE_AUTH", func(c *Config) *string { return &c.Auth }},
+	{"POCKETBASE_AUTH_COLLECTION", func(c *Config) *string { return &c.AuthCollection }},
+	{"POCKETBASE_EMAIL", func(c *Config) *string { return &c.Email }},
+	{"POCKETBASE_PASSWORD", func(c *Config) *string { return &c.Password }},
+	{"POCKETBASE_TOKEN", func(c *Config) *string { return &c.Token }},
+}
+
+// LoadConfigFile overlays the JSON config file at path onto c; keys the
+// file leaves out keep their current values
+func (c *Config) LoadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadEnv overlays the POCKETBASE_* environment variables onto c, reading
+// a .env file in the working directory first if there is one. Unset or
+// empty variables keep the current values.
+func (c *Config) LoadEnv() {
+	// A missing .env file is fine when the variables are already set
+	_ = godotenv.Load()
+
+	for _, v := range envVars {
+		if value := os.Getenv(v.name); value != "" {
+			*v.field(c) = value
+		}
+	}
+}
+
+// Validate checks that c names a server and collection and carries the
+// credentials its auth mode needs
+func (c Config) Validate() error {
+	if c.URL == "" {
+		return errors.New("no PocketBase URL configured")
+	}
+	if c.Collection == "" {
+		return errors.New("no PocketBase collection configured")
+	}
+
+	switch c.Auth {
+	case AuthSuperuser, AuthUser:
+		if c.Email == "" || c.Password == "" {
+			return fmt.Errorf("%s auth needs an email and password: set POCKETBASE_EMAIL and POCKETBASE_PASSWORD in .env file", c.Auth)
+		}
+	case AuthToken:
+		if c.Token == "" {
+			return errors.New("token auth needs a token: set POCKETBASE_TOKEN in .env file")
+		}
+		switch c.AuthCollection {
+		case "", "_superusers", "users":
+		default:
+			return fmt.Errorf("token auth supports the _superusers and users collections, not %q", c.AuthCollection)
+		}
+	default:
+		return fmt.Errorf("unknown auth mode %q: must be %s, %s or %s", c.Auth, AuthSuperuser, AuthUser, AuthToken)
+	}
+	return nil
+}
+
+// clientOption returns the SDK option logging in the way c describes
+func (c Config) clientOption() pocketbase.ClientOption {
+	switch c.Auth {
+	case AuthUser:
+		collection := c.AuthCollection
+		if collection == "" {
+			collection = "users"
+		}
+		return pocketbase.WithUserEmailPasswordAndCollection(c.Email, c.Password, collection)
+	case AuthToken:
+		if c.AuthCollection == "users" {
+			return pocketbase.WithUserToken(c.Token)
+		}
+		return pocketbase.WithSuperuserToken(c.Token)
+	default:
+		return pocketbase.WithSuperuserEmailPassword(c.Email, c.Password)
+	}
+}
+
+// String describes c without its secrets, for log lines
+func (c Config) String() string {
+	who := c.Email
+	if c.Auth == AuthToken {
+		who = "token"
+	}
+	return fmt.Sprintf("%s/%s as %s (%s)", strings.TrimSuffix(c.URL, "/"), c.Collection, who, c.Auth)
+}
@@ -2,200 +2,266 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
-
-	"github.com/habibrosyad/pocketbase-go-sdk"
-	"github.com/joho/godotenv"
 )
 
-// SudokuData represents the structure of a sudoku puzzle
-type SudokuData struct {
-	Grid      [][]int `json:"grid"`
-	Solution  [][]int `json:"solution"`
-	Regions   [][]int `json:"regions"`
-	BoxWidth  int     `json:"boxWidth"`
-	BoxHeight int     `json:"boxHeight"`
-}
-
-// SudokuRecord represents a record in the PocketBase database
-type SudokuRecord struct {
-	ID         string     `json:"id"`
-	Sudoku     SudokuData `json:"sudoku"`
-	Difficulty string     `json:"difficulty"`
-	Size       string     `json:"size"`
-	Layout     string     `json:"layout"`
-	Created    string     `json:"created"`
-	Updated    string     `json:"updated"`
+// Store keeps generated sudokus
+type Store interface {
+	// Save validates and stores a new sudoku, giving it a code if it has
+	// none, and returns its ID. It fails with ErrExists if the ID is taken,
+	// ErrDuplicate if an isomorph is stored and ErrCodeTaken if another
+	// sudoku has its code.
+	Save(rec *SudokuRecord) (string, error)
+	// Get loads one sudoku, failing with ErrNotFound if it does not exist
+	Get(id string) (*SudokuRecord, error)
+	// GetByCode loads the sudoku with the given short code, failing with
+	// ErrNotFound if no sudoku has it
+	GetByCode(code string) (*SudokuRecord, error)
+	// GetByFingerprint loads a sudoku isomorphic to ones with the given
+	// fingerprint, failing with ErrNotFound if there is none
+	GetByFingerprint(fingerprint string) (*SudokuRecord, error)
+	// List returns one page of sudokus matching opts
+	List(opts ListOptions) ([]*SudokuRecord, error)
+	// Exists reports whether a sudoku with this ID is stored
+	Exists(id string) (bool, error)
+	// Delete removes a sudoku, failing with ErrNotFound if it does not exist
+	Delete(id string) error
 }
 
-var client *pocketbase.Client
+var (
+	// ErrNotFound is returned when no sudoku has the requested ID
+	ErrNotFound = errors.New("sudoku not found")
+	// ErrExists is returned when saving a sudoku whose ID is already taken
+	ErrExists = errors.New("sudoku already exists")
+	// ErrDuplicate is returned when saving a sudoku that only differs from
+	// a stored one by relabeling, swaps or rotation
+	ErrDuplicate = errors.New("isomorphic sudoku already exists")
+)
 
-func init() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		fmt.Println("⚠️ Warning: No .env file found")
-	}
+// ListOptions selects and orders the sudokus returned by List. Zero
+// filters match everything.
+type ListOptions struct {
+	// Page is 1-based; PerPage defaults to 30
+	Page    int
+	PerPage int
+	// Difficulty and Size match the stored values exactly
+	Difficulty float64
+	Size       int
+	// Layout is "jigsaw", "regular" for any box layout, or a box layout
+	// such as "3x4"
+	Layout string
+	// Sort is one of SortFields, "created" by default
+	Sort string
+	Desc bool
+}
 
-	email := os.Getenv("POCKETBASE_EMAIL")
-	password := os.Getenv("POCKETBASE_PASSWORD")
+const defaultPerPage = 30
 
-	// Create client with superuser authentication
-	client = pocketbase.NewClient("https://base.mljr.eu",
-		pocketbase.WithSuperuserEmailPassword(email, password))
+// SortFields are the record fields List can order by; every store rejects
+// any other Sort
+var SortFields = []string{"created", "updated", "id", "code", "difficulty", "size", "layout"}
 
-	// Attempt initial authorization
-	if err := client.Authorize(); err != nil {
-		fmt.Printf("⚠️ Initial authorization failed: %v\n", err)
+// sortField returns the field opts.Sort names, "created" when it is empty
+func (opts ListOptions) sortField() (string, error) {
+	if opts.Sort == "" {
+		return "created", nil
 	}
+	if !slices.Contains(SortFields, opts.Sort) {
+		return "", fmt.Errorf("invalid sort field %q: want one of %s", opts.Sort, strings.Join(SortFields, ", "))
+	}
+	return opts.Sort, nil
 }
 
-// Authenticate tries to authenticate with PocketBase
-func Authenticate() error {
-	// Simple authorization check
-	err := client.Authorize()
-	if err != nil {
-		return fmt.Errorf("authentication failed: %v", err)
-	}
-
-	// Start the re-authentication timer
-	go func() {
-		ticker := time.NewTicker(30 * time.Minute)
-		for range ticker.C {
-			if err := client.Authorize(); err != nil {
-				fmt.Printf("⚠️ Re-authentication failed: %v\n", err)
-			} else {
-				fmt.Println("🔄 Successfully re-authenticated with PocketBase")
-			}
-		}
-	}()
-	return nil
+// timeFormat is how PocketBase writes created and updated timestamps
+const timeFormat = "2006-01-02 15:04:05.000Z"
+
+// record is a sudoku as stored: the puzzle itself as a JSON string next to
+// a few text fields to filter on, the layout of the PocketBase collection
+type record struct {
+	ID          string `json:"id"`
+	Code        string `json:"code"`
+	Fingerprint string `json:"fingerprint"`
+	Sudoku      string `json:"sudoku"`
+	Difficulty  string `json:"difficulty"`
+	Size        string `json:"size"`
+	Layout      string `json:"layout"`
+	Created     string `json:"created"`
+	Updated     string `json:"updated"`
 }
 
-func UploadSudoku(sudokuData map[string]interface{}) (*pocketbase.ResponseCreate, error) {
-	// Validate ID length
-	id, ok := sudokuData["id"].(string)
-	if !ok || len(id) > 6 {
-		return nil, fmt.Errorf("invalid ID: must be a string of max 6 characters")
+// toRecord encodes rec for storage, stamping it as created now if it has
+// no timestamps yet
+func toRecord(rec *SudokuRecord) (record, error) {
+	sudokuJSON, err := json.Marshal(rec.Sudoku)
+	if err != nil {
+		return record{}, fmt.Errorf("failed to marshal sudoku data: %v", err)
 	}
 
-	layoutConfig := "jigsaw"
-	if sudokuData["layoutType"] != "jigsaw" {
-		layoutConfig = fmt.Sprintf("%dx%d",
-			sudokuData["boxWidth"].(int),
-			sudokuData["boxHeight"].(int))
+	created, updated := rec.Created, rec.Updated
+	if created.IsZero() {
+		created = time.Now()
+	}
+	if updated.IsZero() {
+		updated = created
 	}
 
-	sudokuJSON, err := json.Marshal(map[string]interface{}{
-		"grid":      sudokuData["grid"],
-		"solution":  sudokuData["solution"],
-		"regions":   sudokuData["regions"],
-		"boxWidth":  sudokuData["boxWidth"],
-		"boxHeight": sudokuData["boxHeight"],
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal sudoku data: %v", err)
+	return record{
+		ID:          rec.ID,
+		Code:        rec.Code,
+		Fingerprint: rec.Fingerprint,
+		Sudoku:      string(sudokuJSON),
+		Difficulty:  strconv.FormatFloat(rec.Difficulty, 'f', -1, 64),
+		Size:        strconv.Itoa(rec.Size),
+		Layout:      rec.Layout,
+		Created:     created.UTC().Format(timeFormat),
+		Updated:     updated.UTC().Format(timeFormat),
+	}, nil
+}
+
+// fields returns the columns of r as sent to PocketBase, which sets the
+// timestamps itself
+func (r record) fields() map[string]any {
+	return map[string]any{
+		"id":          r.ID,
+		"code":        r.Code,
+		"fingerprint": r.Fingerprint,
+		"sudoku":      r.Sudoku,
+		"difficulty":  r.Difficulty,
+		"size":        r.Size,
+		"layout":      r.Layout,
 	}
+}
 
-	data := map[string]any{
-		"id":         id,
-		"sudoku":     string(sudokuJSON),
-		"difficulty": fmt.Sprintf("%v", sudokuData["difficulty"]),
-		"size":       fmt.Sprintf("%v", sudokuData["size"]),
-		"layout":     layoutConfig,
+// decode turns a stored record back into a SudokuRecord
+func (r record) decode() (*SudokuRecord, error) {
+	rec := &SudokuRecord{ID: r.ID, Code: r.Code, Fingerprint: r.Fingerprint, Layout: r.Layout}
+	if err := json.Unmarshal([]byte(r.Sudoku), &rec.Sudoku); err != nil {
+		return nil, fmt.Errorf("sudoku %s: failed to unmarshal sudoku data: %v", r.ID, err)
 	}
 
-	// Check if record with this ID already exists
-	exists, err := SudokuExists(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check if sudoku exists: %v", err)
+	var err error
+	if rec.Difficulty, err = strconv.ParseFloat(r.Difficulty, 64); err != nil {
+		return nil, fmt.Errorf("sudoku %s: invalid difficulty %q", r.ID, r.Difficulty)
 	}
-	if exists {
-		return nil, fmt.Errorf("sudoku with ID %s already exists", id)
+	if rec.Size, err = strconv.Atoi(r.Size); err != nil {
+		return nil, fmt.Errorf("sudoku %s: invalid size %q", r.ID, r.Size)
 	}
+	// Timestamps are informational, so an unexpected format leaves them zero
+	rec.Created, _ = time.Parse(timeFormat, r.Created)
+	rec.Updated, _ = time.Parse(timeFormat, r.Updated)
+	return rec, nil
+}
 
-	record, err := client.Create("sudokus", data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to upload sudoku: %v", err)
+// matches reports whether rec passes the filters in opts
+func (opts ListOptions) matches(rec *SudokuRecord) bool {
+	if opts.Difficulty != 0 && rec.Difficulty != opts.Difficulty {
+		return false
+	}
+	if opts.Size != 0 && rec.Size != opts.Size {
+		return false
+	}
+	switch opts.Layout {
+	case "":
+		return true
+	case "regular":
+		return rec.Layout != "jigsaw"
+	default:
+		return rec.Layout == opts.Layout
 	}
-	return &record, nil
 }
 
-func GetSudoku(id string) (map[string]interface{}, error) {
-	record, err := client.One("sudokus", id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load sudoku %s: %v", id, err)
+// less orders records by the field named in opts.Sort, which sortField
+// has checked
+func (opts ListOptions) less(a, b *SudokuRecord) bool {
+	if opts.Desc {
+		a, b = b, a
 	}
-
-	var sudokuData map[string]interface{}
-	err = json.Unmarshal([]byte(record["sudoku"].(string)), &sudokuData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal sudoku data: %v", err)
+	switch opts.Sort {
+	case "id":
+		return a.ID < b.ID
+	case "code":
+		return a.Code < b.Code
+	case "difficulty":
+		return a.Difficulty < b.Difficulty
+	case "size":
+		return a.Size < b.Size
+	case "layout":
+		return a.Layout < b.Layout
+	case "updated":
+		return a.Updated.Before(b.Updated)
+	default:
+		return a.Created.Before(b.Created)
 	}
+}
 
-	result := map[string]interface{}{
-		"id":         record["id"],
-		"difficulty": record["difficulty"],
-		"size":       record["size"],
-		"layoutType": record["layout"],
-		"created":    record["created"],
-		"updated":    record["updated"],
+// listRecords filters, sorts and pages records in memory for the stores
+// that cannot query
+func listRecords(records []record, opts ListOptions) ([]*SudokuRecord, error) {
+	if _, err := opts.sortField(); err != nil {
+		return nil, err
 	}
-
-	for k, v := range sudokuData {
-		result[k] = v
+	var matched []*SudokuRecord
+	for _, r := range records {
+		rec, err := r.decode()
+		if err != nil {
+			return nil, err
+		}
+		if opts.matches(rec) {
+			matched = append(matched, rec)
+		}
 	}
 
-	return result, nil
+	sort.SliceStable(matched, func(i, j int) bool {
+		return opts.less(matched[i], matched[j])
+	})
+
+	page, perPage := pageSize(opts)
+	start := min((page-1)*perPage, len(matched))
+	end := min(start+perPage, len(matched))
+	return matched[start:end], nil
 }
 
-func ListSudokus(page int, perPage int, filters map[string]string, sortField string, sortOrder string) (*pocketbase.ResponseList[map[string]any], error) {
-	var filterRules []string
-
-	if diff, ok := filters["difficulty"]; ok {
-		filterRules = append(filterRules, fmt.Sprintf("difficulty >= %s && difficulty <= %s", diff, diff))
-	}
-	if size, ok := filters["size"]; ok {
-		filterRules = append(filterRules, fmt.Sprintf("size = \"%s\"", size))
-	}
-	if layout, ok := filters["layout"]; ok {
-		switch layout {
-		case "jigsaw":
-			filterRules = append(filterRules, "layout = \"jigsaw\"")
-		case "regular":
-			filterRules = append(filterRules, "layout != \"jigsaw\"")
-		default:
-			if strings.Contains(layout, "x") {
-				filterRules = append(filterRules, fmt.Sprintf("layout = \"%s\"", layout))
-			}
-		}
+// pageSize applies the defaults for an unset page and page size
+func pageSize(opts ListOptions) (page, perPage int) {
+	page, perPage = opts.Page, opts.PerPage
+	if page < 1 {
+		page = 1
 	}
-
-	sort := sortField
-	if sortOrder == "desc" {
-		sort = "-" + sortField
+	if perPage < 1 {
+		perPage = defaultPerPage
 	}
+	return page, perPage
+}
 
-	params := pocketbase.ParamsList{
-		Page:    page,
-		Size:    perPage,
-		Sort:    sort,
-		Filters: strings.Join(filterRules, " && "),
+// checkDuplicate fails with ErrDuplicate if the store holds an isomorph of
+// rec under another ID. byFingerprint looks fingerprints up in the store.
+func checkDuplicate(rec *SudokuRecord, byFingerprint func(fingerprint string) (*SudokuRecord, error)) error {
+	if rec.Fingerprint == "" {
+		return nil
 	}
-
-	result, err := client.List("sudokus", params)
-	return &result, err
+	other, err := byFingerprint(rec.Fingerprint)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if other.ID != rec.ID {
+		return fmt.Errorf("%w: %s is an isomorph of %s", ErrDuplicate, rec.ID, other.ID)
+	}
+	return nil
 }
 
-func SudokuExists(id string) (bool, error) {
-	_, err := client.One("sudokus", id)
-	if err != nil {
-		if strings.Contains(err.Error(), "404") {
-			return false, nil
-		}
-		return false, err
+// checkID rejects IDs that cannot be used as a file name or record key
+func checkID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("invalid ID %q", id)
 	}
-	return true, nil
+	return nil
 }
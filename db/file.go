@@ -0,0 +1,174 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore keeps each sudoku as <id>.json in a directory, so puzzles can
+// be stored, copied and inspected without any server
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %v", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
+
+func (f *FileStore) Save(rec *SudokuRecord) (string, error) {
+	if err := rec.Validate(); err != nil {
+		return "", err
+	}
+	if err := checkDuplicate(rec, f.GetByFingerprint); err != nil {
+		return "", err
+	}
+	if err := assignCode(rec, f.GetByCode); err != nil {
+		return "", err
+	}
+	stored, err := toRecord(rec)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sudoku record: %v", err)
+	}
+
+	// O_EXCL makes the existence check and the write a single step, even
+	// against other processes sharing the directory
+	file, err := os.OpenFile(f.path(rec.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("%w: %s", ErrExists, rec.ID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save sudoku: %v", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(f.path(rec.ID))
+		return "", fmt.Errorf("failed to save sudoku: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to save sudoku: %v", err)
+	}
+	return rec.ID, nil
+}
+
+func (f *FileStore) Get(id string) (*SudokuRecord, error) {
+	rec, err := f.load(id)
+	if err != nil {
+		return nil, err
+	}
+	return rec.decode()
+}
+
+// GetByCode reads every record, since files are named by ID only
+func (f *FileStore) GetByCode(code string) (*SudokuRecord, error) {
+	records, err := f.loadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		if rec.Code == code {
+			return rec.decode()
+		}
+	}
+	return nil, fmt.Errorf("%w: code %s", ErrNotFound, code)
+}
+
+func (f *FileStore) GetByFingerprint(fingerprint string) (*SudokuRecord, error) {
+	records, err := f.loadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		if rec.Fingerprint == fingerprint {
+			return rec.decode()
+		}
+	}
+	return nil, fmt.Errorf("%w: fingerprint %s", ErrNotFound, fingerprint)
+}
+
+// load reads the record stored under id
+func (f *FileStore) load(id string) (record, error) {
+	if err := checkID(id); err != nil {
+		return record{}, err
+	}
+	data, err := os.ReadFile(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return record{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return record{}, fmt.Errorf("failed to load sudoku %s: %v", id, err)
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return record{}, fmt.Errorf("failed to load sudoku %s: %v", id, err)
+	}
+	return rec, nil
+}
+
+func (f *FileStore) List(opts ListOptions) ([]*SudokuRecord, error) {
+	records, err := f.loadAll()
+	if err != nil {
+		return nil, err
+	}
+	return listRecords(records, opts)
+}
+
+// loadAll reads every record in the directory
+func (f *FileStore) loadAll() ([]record, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sudokus: %v", err)
+	}
+
+	var records []record
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		rec, err := f.load(id)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func (f *FileStore) Exists(id string) (bool, error) {
+	if err := checkID(id); err != nil {
+		return false, err
+	}
+	_, err := os.Stat(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (f *FileStore) Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	err := os.Remove(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return err
+}
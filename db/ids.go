@@ -0,0 +1,264 @@
+package db
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"sudoku_gen_go/internal/types"
+)
+
+// IDs are 15 base36 characters, the shape PocketBase uses for its own
+// record IDs, taken from a SHA-256 of the puzzle. Codes are the shortest
+// prefix of the ID, at least 6 characters, that no other sudoku uses, so
+// people can still read them out.
+//
+// Records saved before this scheme have IDs of up to 6 characters from a
+// 32-bit string hash, shorter when the hash was small. MigrateLegacyIDs moves them to their new ID and keeps the
+// old ID as their code, so links that use it keep working. PocketBase
+// collections need a text field "code" for this, and a text field
+// "fingerprint" for the isomorph check.
+const (
+	idLength      = 15
+	minCodeLength = 6
+	legacyIDLen   = 6
+)
+
+var (
+	// ErrCodeTaken is returned when saving a sudoku whose code is already
+	// used by a different sudoku
+	ErrCodeTaken = errors.New("sudoku code already taken")
+
+	idModulus = new(big.Int).Exp(big.NewInt(36), big.NewInt(idLength), nil)
+)
+
+// PuzzleID returns the ID of a puzzle: a hash of its size, layout,
+// variants, givens, regions and cages. Regions and cages are compared as
+// sets of cells, so their order and the order of cells within them do not
+// matter, and the difficulty the puzzle was generated at is not part of
+// its identity.
+func PuzzleID(grid *types.Grid) string {
+	sum := sha256.Sum256([]byte(canonicalForm(grid)))
+	n := new(big.Int).SetBytes(sum[:16])
+	n.Mod(n, idModulus)
+	id := n.Text(36)
+	return strings.Repeat("0", idLength-len(id)) + id
+}
+
+// canonicalForm serialises everything that makes a puzzle distinct
+func canonicalForm(grid *types.Grid) string {
+	var b strings.Builder
+	layout := "jigsaw"
+	if grid.Type != types.Jigsaw {
+		layout = fmt.Sprintf("%dx%d", grid.BoxWidth, grid.BoxHeight)
+	}
+	fmt.Fprintf(&b, "sudoku/v1 %d %s\n", grid.Size, layout)
+	if len(grid.Variants) > 0 {
+		// Only written when present, so plain puzzles keep their IDs
+		variants := make([]string, len(grid.Variants))
+		for i, v := range grid.Variants {
+			variants[i] = string(v)
+		}
+		slices.Sort(variants)
+		fmt.Fprintf(&b, "variants %s\n", strings.Join(variants, ","))
+	}
+
+	for _, row := range grid.Puzzle {
+		for j, num := range row {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(num))
+		}
+		b.WriteByte('\n')
+	}
+
+	regions := grid.SubGrids
+	if len(regions) == 0 && grid.BoxWidth > 0 && grid.BoxHeight > 0 {
+		regions = types.BoxRegions(grid.Size, grid.BoxWidth, grid.BoxHeight)
+	}
+	sorted := make([][]int, len(regions))
+	for i, region := range regions {
+		sorted[i] = slices.Clone(region)
+		slices.Sort(sorted[i])
+	}
+	slices.SortFunc(sorted, slices.Compare)
+	for _, region := range sorted {
+		for j, cell := range region {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(cell))
+		}
+		b.WriteByte('\n')
+	}
+
+	if len(grid.Cages) > 0 {
+		cages := make([]string, len(grid.Cages))
+		for i, cage := range grid.Cages {
+			cells := slices.Clone(cage.Cells)
+			slices.Sort(cells)
+			parts := make([]string, len(cells))
+			for j, cell := range cells {
+				parts[j] = strconv.Itoa(cell)
+			}
+			cages[i] = fmt.Sprintf("cage %d %s", cage.Sum, strings.Join(parts, ","))
+		}
+		slices.Sort(cages)
+		b.WriteString(strings.Join(cages, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// isBase36 reports whether s is between minLen and maxLen lowercase
+// base36 characters, the shape of IDs and codes
+func isBase36(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// isHex reports whether s is a lowercase hex string such as a fingerprint
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isBoxLayout reports whether s is a box layout such as "3x4"
+func isBoxLayout(s string) bool {
+	width, height, found := strings.Cut(s, "x")
+	return found && isDigits(width) && isDigits(height)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// assignCode gives rec a code no other sudoku uses. A code the record
+// already carries is kept if it is free; otherwise the shortest free
+// prefix of the ID is used. byCode looks codes up in the store.
+func assignCode(rec *SudokuRecord, byCode func(code string) (*SudokuRecord, error)) error {
+	free := func(code string) (bool, error) {
+		other, err := byCode(code)
+		if errors.Is(err, ErrNotFound) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return other.ID == rec.ID, nil
+	}
+
+	if rec.Code != "" {
+		ok, err := free(rec.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrCodeTaken, rec.Code)
+		}
+		return nil
+	}
+
+	for n := minCodeLength; n <= len(rec.ID); n++ {
+		ok, err := free(rec.ID[:n])
+		if err != nil {
+			return err
+		}
+		if ok {
+			rec.Code = rec.ID[:n]
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: every prefix of %s", ErrCodeTaken, rec.ID)
+}
+
+// Migration reports what MigrateLegacyIDs did with one legacy record
+type Migration struct {
+	OldID string
+	NewID string
+	Code  string
+	// Duplicate is set when the puzzle is already stored under its new
+	// ID; the legacy record is then left alone
+	Duplicate bool
+	Err       error
+}
+
+// MigrateLegacyIDs moves every record with a legacy ID of up to 6
+// characters to its PuzzleID, keeping the legacy ID as its code where that
+// code is still free. With dryRun it only reports what it would do.
+func MigrateLegacyIDs(store Store, dryRun bool) ([]Migration, error) {
+	// Collect first so paging is not disturbed by the moves
+	var legacy []*SudokuRecord
+	for page := 1; ; page++ {
+		recs, err := store.List(ListOptions{Page: page, PerPage: 100, Sort: "id"})
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			if len(rec.ID) <= legacyIDLen {
+				legacy = append(legacy, rec)
+			}
+		}
+		if len(recs) < 100 {
+			break
+		}
+	}
+
+	migrations := make([]Migration, 0, len(legacy))
+	for _, rec := range legacy {
+		m := Migration{OldID: rec.ID, NewID: PuzzleID(rec.Grid()), Code: rec.ID}
+		migrations = append(migrations, m)
+		last := &migrations[len(migrations)-1]
+
+		exists, err := store.Exists(m.NewID)
+		if err != nil {
+			last.Err = err
+			continue
+		}
+		if exists {
+			last.Duplicate = true
+			continue
+		}
+		if dryRun {
+			continue
+		}
+
+		moved := *rec
+		moved.ID, moved.Code = m.NewID, m.OldID
+		moved.Fingerprint, _ = rec.Grid().Fingerprint()
+		_, err = store.Save(&moved)
+		if errors.Is(err, ErrCodeTaken) {
+			// Another sudoku already answers to the old ID as its code
+			moved.Code = ""
+			_, err = store.Save(&moved)
+		}
+		if err != nil {
+			last.Err = err
+			continue
+		}
+		last.Code = moved.Code
+		if err := store.Delete(m.OldID); err != nil {
+			last.Err = fmt.Errorf("saved as %s but could not delete %s: %v", m.NewID, m.OldID, err)
+		}
+	}
+	return migrations, nil
+}
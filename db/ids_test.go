@@ -0,0 +1,117 @@
+package db_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sudoku_gen_go/db"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+	"testing"
+	"time"
+)
+
+// puzzles are 9x9 puzzles with unique solutions, none an isomorph of
+// another, written row by row with 0 for empty cells
+var puzzles = []string{
+	"500800000010002700000010090070001840080007006600000000005038060400000037000500010",
+	"278000000610007000000000000006800100350040009000003200005091007107000040063005090",
+	"000000004030807000000000290107000000400070600053200009009080030070300000000610740",
+	"070000001006080700009007645000001006000005000400030580890070000005000000103050907",
+	"400000805756000400000006000009000000000305204800102006000420001000980002007001900",
+}
+
+// testRecord builds the record of one of the puzzles at the given level
+func testRecord(t *testing.T, puzzle string, difficulty int) *db.SudokuRecord {
+	t.Helper()
+	grid := types.NewGrid(9, types.Normal)
+	grid.SubGrids = types.BoxRegions(9, 3, 3)
+	for i, ch := range puzzle {
+		grid.Puzzle[i/9][i%9] = int(ch - '0')
+	}
+	solution, err := solver.Solve(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid.Solution = solution
+	rec, err := db.NewRecord(grid, difficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// writeLegacy stores rec under a legacy ID the way old versions wrote
+// it, bypassing Save, which only takes current IDs
+func writeLegacy(t *testing.T, dir, id string, rec *db.SudokuRecord) {
+	t.Helper()
+	sudoku, err := json.Marshal(rec.Sudoku)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+	data, err := json.Marshal(map[string]string{
+		"id":         id,
+		"sudoku":     string(sudoku),
+		"difficulty": strconv.FormatFloat(rec.Difficulty, 'f', -1, 64),
+		"size":       strconv.Itoa(rec.Size),
+		"layout":     rec.Layout,
+		"created":    created,
+		"updated":    created,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateLegacyIDs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := db.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small hashes gave legacy IDs shorter than 6 characters
+	legacy := map[string]*db.SudokuRecord{
+		"k3x9zq": testRecord(t, puzzles[0], 3),
+		"1a2b":   testRecord(t, puzzles[1], 3),
+		"z":      testRecord(t, puzzles[2], 3),
+	}
+	for id, rec := range legacy {
+		writeLegacy(t, dir, id, rec)
+	}
+	current := testRecord(t, puzzles[3], 3)
+	if _, err := store.Save(current); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := db.MigrateLegacyIDs(store, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != len(legacy) {
+		t.Fatalf("migrated %d records, want %d", len(migrations), len(legacy))
+	}
+	for _, m := range migrations {
+		rec := legacy[m.OldID]
+		if rec == nil || m.Err != nil || m.Duplicate {
+			t.Fatalf("unexpected migration %+v", m)
+		}
+		if m.NewID != rec.ID || m.Code != m.OldID {
+			t.Errorf("%s moved to %s with code %s, want %s with code %s", m.OldID, m.NewID, m.Code, rec.ID, m.OldID)
+		}
+		if exists, _ := store.Exists(m.OldID); exists {
+			t.Errorf("%s is still stored", m.OldID)
+		}
+		moved, err := store.GetByCode(m.OldID)
+		if err != nil || moved.ID != rec.ID {
+			t.Errorf("code %s does not find %s: %v", m.OldID, rec.ID, err)
+		}
+	}
+	if got, err := store.Get(current.ID); err != nil || got.Code != current.Code {
+		t.Errorf("current record changed: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryStore keeps sudokus in a map, for tests and one-off runs that do
+// not need to persist anything
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]record
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]record)}
+}
+
+func (m *MemoryStore) Save(rec *SudokuRecord) (string, error) {
+	if err := rec.Validate(); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[rec.ID]; ok {
+		return "", fmt.Errorf("%w: %s", ErrExists, rec.ID)
+	}
+	if err := checkDuplicate(rec, m.byFingerprint); err != nil {
+		return "", err
+	}
+	if err := assignCode(rec, m.byCode); err != nil {
+		return "", err
+	}
+	stored, err := toRecord(rec)
+	if err != nil {
+		return "", err
+	}
+	m.records[rec.ID] = stored
+	return rec.ID, nil
+}
+
+func (m *MemoryStore) Get(id string) (*SudokuRecord, error) {
+	m.mu.Lock()
+	rec, ok := m.records[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return rec.decode()
+}
+
+func (m *MemoryStore) GetByCode(code string) (*SudokuRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byCode(code)
+}
+
+// byCode finds a record by code; the caller holds m.mu
+func (m *MemoryStore) byCode(code string) (*SudokuRecord, error) {
+	for _, rec := range m.records {
+		if rec.Code == code {
+			return rec.decode()
+		}
+	}
+	return nil, fmt.Errorf("%w: code %s", ErrNotFound, code)
+}
+
+func (m *MemoryStore) GetByFingerprint(fingerprint string) (*SudokuRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byFingerprint(fingerprint)
+}
+
+// byFingerprint finds a record by fingerprint; the caller holds m.mu
+func (m *MemoryStore) byFingerprint(fingerprint string) (*SudokuRecord, error) {
+	for _, rec := range m.records {
+		if rec.Fingerprint == fingerprint {
+			return rec.decode()
+		}
+	}
+	return nil, fmt.Errorf("%w: fingerprint %s", ErrNotFound, fingerprint)
+}
+
+func (m *MemoryStore) List(opts ListOptions) ([]*SudokuRecord, error) {
+	m.mu.Lock()
+	records := make([]record, 0, len(m.records))
+	for _, rec := range m.records {
+		records = append(records, rec)
+	}
+	m.mu.Unlock()
+	return listRecords(records, opts)
+}
+
+func (m *MemoryStore) Exists(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.records[id]
+	return ok, nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(m.records, id)
+	return nil
+}
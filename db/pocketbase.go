@@ -0,0 +1,241 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habibrosyad/pocketbase-go-sdk"
+)
+
+// PocketBase stores sudokus in a PocketBase collection. Creating one does
+// not touch the network; Authenticate does.
+type PocketBase struct {
+	client     *pocketbase.Client
+	collection string
+	reauthOnce sync.Once
+}
+
+// NewPocketBase returns a store for the server and collection in cfg
+func NewPocketBase(cfg Config) (*PocketBase, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &PocketBase{
+		client:     pocketbase.NewClient(strings.TrimSuffix(cfg.URL, "/"), cfg.clientOption()),
+		collection: cfg.Collection,
+	}, nil
+}
+
+// ConnectPocketBase returns an authenticated store for cfg
+func ConnectPocketBase(cfg Config) (*PocketBase, error) {
+	store, err := NewPocketBase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Authenticate(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Authenticate tries to authenticate with PocketBase
+func (p *PocketBase) Authenticate() error {
+	// Simple authorization check
+	err := p.client.Authorize()
+	if err != nil {
+		return fmt.Errorf("authentication failed: %v", err)
+	}
+
+	// Start the re-authentication timer
+	p.reauthOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(30 * time.Minute)
+			for range ticker.C {
+				if err := p.client.Authorize(); err != nil {
+					fmt.Printf("⚠️ Re-authentication failed: %v\n", err)
+				} else {
+					fmt.Println("🔄 Successfully re-authenticated with PocketBase")
+				}
+			}
+		}()
+	})
+	return nil
+}
+
+func (p *PocketBase) Save(sudoku *SudokuRecord) (string, error) {
+	// Validate before any network call
+	if err := sudoku.Validate(); err != nil {
+		return "", err
+	}
+
+	// Check if record with this ID already exists
+	exists, err := p.Exists(sudoku.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if sudoku exists: %v", err)
+	}
+	if exists {
+		return "", fmt.Errorf("%w: %s", ErrExists, sudoku.ID)
+	}
+	if err := checkDuplicate(sudoku, p.GetByFingerprint); err != nil {
+		return "", err
+	}
+	if err := assignCode(sudoku, p.GetByCode); err != nil {
+		return "", err
+	}
+	rec, err := toRecord(sudoku)
+	if err != nil {
+		return "", err
+	}
+
+	created, err := p.client.Create(p.collection, rec.fields())
+	if err != nil {
+		return "", fmt.Errorf("failed to upload sudoku: %v", err)
+	}
+	return created.ID, nil
+}
+
+func (p *PocketBase) Get(id string) (*SudokuRecord, error) {
+	one, err := p.client.One(p.collection, id)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to load sudoku %s: %v", id, err)
+	}
+	return fromPocketBase(one).decode()
+}
+
+func (p *PocketBase) GetByCode(code string) (*SudokuRecord, error) {
+	if !isBase36(code, 1, idLength) {
+		return nil, fmt.Errorf("invalid code %q", code)
+	}
+	return p.findOne("code", code)
+}
+
+func (p *PocketBase) GetByFingerprint(fingerprint string) (*SudokuRecord, error) {
+	if !isHex(fingerprint) {
+		return nil, fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+	return p.findOne("fingerprint", fingerprint)
+}
+
+// findOne loads the first record whose field equals value; callers check
+// value so it cannot break out of the filter string
+func (p *PocketBase) findOne(field, value string) (*SudokuRecord, error) {
+	list, err := p.client.List(p.collection, pocketbase.ParamsList{
+		Page:    1,
+		Size:    1,
+		Filters: fmt.Sprintf("%s = \"%s\"", field, value),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s: %v", field, value, err)
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotFound, field, value)
+	}
+	return fromPocketBase(list.Items[0]).decode()
+}
+
+func (p *PocketBase) List(opts ListOptions) ([]*SudokuRecord, error) {
+	var filterRules []string
+
+	if opts.Difficulty != 0 {
+		diff := strconv.FormatFloat(opts.Difficulty, 'f', -1, 64)
+		filterRules = append(filterRules, fmt.Sprintf("difficulty >= %s && difficulty <= %s", diff, diff))
+	}
+	if opts.Size != 0 {
+		filterRules = append(filterRules, fmt.Sprintf("size = \"%d\"", opts.Size))
+	}
+	switch opts.Layout {
+	case "":
+	case "regular":
+		filterRules = append(filterRules, "layout != \"jigsaw\"")
+	default:
+		// The layout goes into the filter string, so only known shapes
+		if opts.Layout != "jigsaw" && !isBoxLayout(opts.Layout) {
+			return nil, fmt.Errorf("invalid layout %q: want jigsaw, regular or a box layout such as 3x3", opts.Layout)
+		}
+		filterRules = append(filterRules, fmt.Sprintf("layout = \"%s\"", opts.Layout))
+	}
+
+	// The sort goes into the query too, so only known fields
+	sort, err := opts.sortField()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Desc {
+		sort = "-" + sort
+	}
+
+	page, perPage := pageSize(opts)
+	params := pocketbase.ParamsList{
+		Page:    page,
+		Size:    perPage,
+		Sort:    sort,
+		Filters: strings.Join(filterRules, " && "),
+	}
+
+	list, err := p.client.List(p.collection, params)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*SudokuRecord, 0, len(list.Items))
+	for _, item := range list.Items {
+		sudoku, err := fromPocketBase(item).decode()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sudoku)
+	}
+	return result, nil
+}
+
+func (p *PocketBase) Exists(id string) (bool, error) {
+	_, err := p.client.One(p.collection, id)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *PocketBase) Delete(id string) error {
+	if err := p.client.Delete(p.collection, id); err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return fmt.Errorf("failed to delete sudoku %s: %v", id, err)
+	}
+	return nil
+}
+
+// fromPocketBase reads a record out of a PocketBase response item
+func fromPocketBase(item map[string]any) record {
+	text := func(key string) string {
+		if v, ok := item[key]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	return record{
+		ID:          text("id"),
+		Code:        text("code"),
+		Fingerprint: text("fingerprint"),
+		Sudoku:      text("sudoku"),
+		Difficulty:  text("difficulty"),
+		Size:        text("size"),
+		Layout:      text("layout"),
+		Created:     text("created"),
+		Updated:     text("updated"),
+	}
+}
+
+// isNotFound reports whether a client error is a 404 from the server
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "404")
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sudoku_gen_go/internal/grader"
+	"sudoku_gen_go/internal/types"
+	"time"
+)
+
+// SudokuData represents the structure of a sudoku puzzle. Grid and
+// Solution are flattened row by row, the way the web app reads them.
+type SudokuData struct {
+	Grid      []int   `json:"grid"`
+	Solution  []int   `json:"solution"`
+	Regions   [][]int `json:"regions"`
+	BoxWidth  int     `json:"boxWidth"`
+	BoxHeight int     `json:"boxHeight"`
+	// Variants lists the variant rules on top of the layout, such as "x"
+	Variants []types.SudokuType `json:"variants,omitempty"`
+	// Cages are the cages of a killer puzzle with their sums
+	Cages []types.Cage `json:"cages,omitempty"`
+	// Seed reproduces the puzzle with the generator; 0 if unknown
+	Seed int64 `json:"seed,omitempty"`
+	// Grade is how hard the puzzle is for a human solver
+	Grade *grader.Result `json:"grade,omitempty"`
+}
+
+// SudokuRecord represents a record in the PocketBase database
+type SudokuRecord struct {
+	// ID is the PuzzleID of the puzzle
+	ID string `json:"id"`
+	// Code is a short, unique prefix of ID for people to type; stores
+	// assign one on Save when it is empty
+	Code string `json:"code"`
+	// Fingerprint is the same for every isomorph of the puzzle, see
+	// types.Grid.Fingerprint; empty for layouts without a canonical form
+	Fingerprint string     `json:"fingerprint"`
+	Sudoku      SudokuData `json:"sudoku"`
+	// Difficulty is the generator level divided by 5, so 0.2 to 1
+	Difficulty float64 `json:"difficulty"`
+	Size       int     `json:"size"`
+	// Layout is "jigsaw" or the box dimensions such as "3x4"
+	Layout  string    `json:"layout"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// NewRecord builds the record for a grid generated at the given level
+// from 1 to 5. It grades the puzzle, which also makes sure the puzzle
+// has exactly one solution, and validates the result.
+func NewRecord(grid *types.Grid, difficulty int) (*SudokuRecord, error) {
+	if difficulty < 1 || difficulty > 5 {
+		return nil, fmt.Errorf("invalid difficulty %d: must be between 1 and 5", difficulty)
+	}
+	if len(grid.Puzzle) != grid.Size || len(grid.Solution) != grid.Size {
+		return nil, fmt.Errorf("invalid grid: puzzle and solution must have %d rows", grid.Size)
+	}
+
+	grade, err := grader.Grade(grid)
+	if err != nil {
+		return nil, err
+	}
+
+	layoutConfig := "jigsaw"
+	if grid.Type != types.Jigsaw {
+		layoutConfig = fmt.Sprintf("%dx%d", grid.BoxWidth, grid.BoxHeight)
+	}
+
+	normalizedDifficulty := float64(difficulty) / 5.0
+
+	// Jigsaw puzzles, variants and nearly empty grids have no fingerprint
+	// and skip the isomorph check
+	fingerprint, _ := grid.Fingerprint()
+
+	rec := &SudokuRecord{
+		ID:          PuzzleID(grid),
+		Fingerprint: fingerprint,
+		Sudoku: SudokuData{
+			Grid:      flatten(grid.Puzzle),
+			Solution:  flatten(grid.Solution),
+			Regions:   grid.SubGrids,
+			BoxWidth:  grid.BoxWidth,
+			BoxHeight: grid.BoxHeight,
+			Variants:  grid.Variants,
+			Cages:     grid.Cages,
+			Seed:      grid.Seed,
+			Grade:     grade,
+		},
+		Difficulty: normalizedDifficulty,
+		Size:       grid.Size,
+		Layout:     layoutConfig,
+	}
+	if err := rec.Validate(); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Validate checks that the record is complete and self-consistent, so a
+// bad record is rejected before it reaches any store
+func (r *SudokuRecord) Validate() error {
+	if !isBase36(r.ID, idLength, idLength) {
+		return fmt.Errorf("invalid ID %q: must be %d base36 characters", r.ID, idLength)
+	}
+	// Assigned codes have at least minCodeLength characters, but legacy
+	// IDs kept as codes can be shorter
+	if r.Code != "" && !isBase36(r.Code, 1, idLength) {
+		return fmt.Errorf("invalid code %q: must be 1 to %d base36 characters", r.Code, idLength)
+	}
+	if r.Fingerprint != "" && !isHex(r.Fingerprint) {
+		return fmt.Errorf("invalid fingerprint %q", r.Fingerprint)
+	}
+	if r.Difficulty <= 0 || r.Difficulty > 1 {
+		return fmt.Errorf("invalid difficulty %v: must be in (0, 1]", r.Difficulty)
+	}
+
+	size, data := r.Size, r.Sudoku
+	if size < 1 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+	if len(data.Grid) != size*size || len(data.Solution) != size*size {
+		return fmt.Errorf("grid and solution must have %d cells", size*size)
+	}
+
+	regions := data.Regions
+	if r.Layout == "jigsaw" {
+		if len(regions) == 0 {
+			return errors.New("jigsaw layout needs regions")
+		}
+	} else {
+		if r.Layout != fmt.Sprintf("%dx%d", data.BoxWidth, data.BoxHeight) {
+			return fmt.Errorf("layout %q does not match %dx%d boxes", r.Layout, data.BoxWidth, data.BoxHeight)
+		}
+		if data.BoxWidth*data.BoxHeight != size {
+			return fmt.Errorf("%dx%d boxes do not tile a %dx%d grid", data.BoxWidth, data.BoxHeight, size, size)
+		}
+		if len(regions) == 0 {
+			regions = types.BoxRegions(size, data.BoxWidth, data.BoxHeight)
+		}
+	}
+	if len(regions) != size {
+		return fmt.Errorf("want %d regions, got %d", size, len(regions))
+	}
+	inRegion := make([]bool, size*size)
+	for i, region := range regions {
+		if len(region) != size {
+			return fmt.Errorf("region %d has %d cells, want %d", i, len(region), size)
+		}
+		for _, cell := range region {
+			if cell < 0 || cell >= size*size || inRegion[cell] {
+				return fmt.Errorf("region %d has invalid cell %d", i, cell)
+			}
+			inRegion[cell] = true
+		}
+	}
+
+	for i, num := range data.Solution {
+		if num < 1 || num > size {
+			return fmt.Errorf("solution has invalid value %d at cell %d", num, i)
+		}
+		if given := data.Grid[i]; given != 0 && given != num {
+			return fmt.Errorf("given %d at cell %d disagrees with the solution", given, i)
+		}
+	}
+	houses := append(append([][]int{}, regions...), lines(size)...)
+	for _, house := range houses {
+		seen := make(map[int]bool, size)
+		for _, cell := range house {
+			if seen[data.Solution[cell]] {
+				return fmt.Errorf("solution repeats %d in a row, column or region", data.Solution[cell])
+			}
+			seen[data.Solution[cell]] = true
+		}
+	}
+
+	grid := r.Grid()
+	if err := grid.CheckVariants(); err != nil {
+		return err
+	}
+	if grid.HasVariant(types.Killer) && len(grid.Cages) == 0 {
+		return errors.New("killer puzzle has no cages")
+	}
+	if err := grid.CheckConstraints(grid.Solution); err != nil {
+		return fmt.Errorf("solution breaks a variant rule: %v", err)
+	}
+	return nil
+}
+
+// Grid converts the record back into a grid as the generator produced it
+func (r *SudokuRecord) Grid() *types.Grid {
+	typ := types.Normal
+	if r.Layout == "jigsaw" {
+		typ = types.Jigsaw
+	}
+	return &types.Grid{
+		Size:      r.Size,
+		BoxWidth:  r.Sudoku.BoxWidth,
+		BoxHeight: r.Sudoku.BoxHeight,
+		Puzzle:    unflatten(r.Sudoku.Grid, r.Size),
+		Solution:  unflatten(r.Sudoku.Solution, r.Size),
+		SubGrids:  r.Sudoku.Regions,
+		Type:      typ,
+		Variants:  r.Sudoku.Variants,
+		Cages:     r.Sudoku.Cages,
+		Seed:      r.Sudoku.Seed,
+	}
+}
+
+// lines returns the cell indices of every row and column
+func lines(size int) [][]int {
+	houses := make([][]int, 0, 2*size)
+	for i := 0; i < size; i++ {
+		row := make([]int, size)
+		col := make([]int, size)
+		for j := 0; j < size; j++ {
+			row[j] = i*size + j
+			col[j] = j*size + i
+		}
+		houses = append(houses, row, col)
+	}
+	return houses
+}
+
+// Flatten 2D arrays into 1D arrays
+func flatten(rows [][]int) []int {
+	flat := make([]int, 0, len(rows)*len(rows))
+	for _, row := range rows {
+		flat = append(flat, row...)
+	}
+	return flat
+}
+
+func unflatten(flat []int, size int) [][]int {
+	if size < 1 || len(flat) != size*size {
+		return nil
+	}
+	rows := make([][]int, size)
+	for i := range rows {
+		rows[i] = append([]int(nil), flat[i*size:(i+1)*size]...)
+	}
+	return rows
+}
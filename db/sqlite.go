@@ -0,0 +1,249 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	// Pure Go driver, so the binary still cross-compiles without cgo
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS sudokus (
+	id          TEXT PRIMARY KEY,
+	code        TEXT,
+	fingerprint TEXT,
+	sudoku      TEXT NOT NULL,
+	difficulty  TEXT NOT NULL,
+	size        TEXT NOT NULL,
+	layout      TEXT NOT NULL,
+	created     TEXT NOT NULL,
+	updated     TEXT NOT NULL
+)`
+
+// sqliteColumns lists the table columns in the order scanRecord reads them
+const sqliteColumns = "id, code, fingerprint, sudoku, difficulty, size, layout, created, updated"
+
+// sortable maps SortFields to their SQL expressions; numbers are stored as text, so they are cast to sort numerically
+var sortable = map[string]string{
+	"id":         "id",
+	"code":       "code",
+	"difficulty": "CAST(difficulty AS REAL)",
+	"size":       "CAST(size AS INTEGER)",
+	"layout":     "layout",
+	"created":    "created",
+	"updated":    "updated",
+}
+
+// SQLite keeps sudokus in an embedded SQLite database file, for teams
+// without a PocketBase server who still want queries and a single file
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path and makes sure the
+// sudokus table exists
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sudokus table: %v", err)
+	}
+	if err := upgradeSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade sudokus table: %v", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// upgradeSchema adds the columns tables created by older versions lack,
+// and their indexes. SQLite cannot add a UNIQUE column, so uniqueness of
+// codes comes from an index.
+func upgradeSchema(db *sql.DB) error {
+	for _, column := range []string{"code", "fingerprint"} {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('sudokus') WHERE name = ?", column).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			if _, err := db.Exec("ALTER TABLE sudokus ADD COLUMN " + column + " TEXT"); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS sudokus_code ON sudokus (code)"); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS sudokus_fingerprint ON sudokus (fingerprint)")
+	return err
+}
+
+// Close closes the database
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLite) Save(sudoku *SudokuRecord) (string, error) {
+	if err := sudoku.Validate(); err != nil {
+		return "", err
+	}
+	if err := checkDuplicate(sudoku, s.GetByFingerprint); err != nil {
+		return "", err
+	}
+	if err := assignCode(sudoku, s.GetByCode); err != nil {
+		return "", err
+	}
+	rec, err := toRecord(sudoku)
+	if err != nil {
+		return "", err
+	}
+
+	// INSERT OR IGNORE turns a taken ID or code into zero affected rows
+	// instead of a driver-specific constraint error. Empty codes are
+	// stored as NULL, which the unique index allows any number of.
+	res, err := s.db.Exec("INSERT OR IGNORE INTO sudokus ("+sqliteColumns+") VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?)",
+		rec.ID, rec.Code, rec.Fingerprint, rec.Sudoku, rec.Difficulty, rec.Size, rec.Layout, rec.Created, rec.Updated)
+	if err != nil {
+		return "", fmt.Errorf("failed to save sudoku: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", fmt.Errorf("failed to save sudoku: %v", err)
+	} else if n == 0 {
+		if exists, _ := s.Exists(rec.ID); !exists {
+			return "", fmt.Errorf("%w: %s", ErrCodeTaken, rec.Code)
+		}
+		return "", fmt.Errorf("%w: %s", ErrExists, rec.ID)
+	}
+	return rec.ID, nil
+}
+
+func (s *SQLite) Get(id string) (*SudokuRecord, error) {
+	row := s.db.QueryRow("SELECT "+sqliteColumns+" FROM sudokus WHERE id = ?", id)
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sudoku %s: %v", id, err)
+	}
+	return rec.decode()
+}
+
+func (s *SQLite) GetByCode(code string) (*SudokuRecord, error) {
+	return s.findOne("code", code)
+}
+
+func (s *SQLite) GetByFingerprint(fingerprint string) (*SudokuRecord, error) {
+	return s.findOne("fingerprint", fingerprint)
+}
+
+// findOne loads the first record whose column equals value; column is
+// always a literal from the callers
+func (s *SQLite) findOne(column, value string) (*SudokuRecord, error) {
+	row := s.db.QueryRow("SELECT "+sqliteColumns+" FROM sudokus WHERE "+column+" = ? LIMIT 1", value)
+	rec, err := scanRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotFound, column, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s: %v", column, value, err)
+	}
+	return rec.decode()
+}
+
+func (s *SQLite) List(opts ListOptions) ([]*SudokuRecord, error) {
+	var where []string
+	var args []any
+
+	if opts.Difficulty != 0 {
+		where = append(where, "CAST(difficulty AS REAL) = ?")
+		args = append(args, opts.Difficulty)
+	}
+	if opts.Size != 0 {
+		where = append(where, "CAST(size AS INTEGER) = ?")
+		args = append(args, opts.Size)
+	}
+	switch opts.Layout {
+	case "":
+	case "regular":
+		where = append(where, "layout != 'jigsaw'")
+	default:
+		where = append(where, "layout = ?")
+		args = append(args, opts.Layout)
+	}
+
+	// Only known column expressions reach the query text
+	field, err := opts.sortField()
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + sqliteColumns + " FROM sudokus"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + sortable[field]
+	if opts.Desc {
+		query += " DESC"
+	}
+
+	page, perPage := pageSize(opts)
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, perPage, (page-1)*perPage)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sudokus: %v", err)
+	}
+	defer rows.Close()
+
+	var result []*SudokuRecord
+	for rows.Next() {
+		rec, err := scanRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sudokus: %v", err)
+		}
+		sudoku, err := rec.decode()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sudoku)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLite) Exists(id string) (bool, error) {
+	var one int
+	err := s.db.QueryRow("SELECT 1 FROM sudokus WHERE id = ?", id).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SQLite) Delete(id string) error {
+	res, err := s.db.Exec("DELETE FROM sudokus WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete sudoku %s: %v", id, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete sudoku %s: %v", id, err)
+	} else if n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+// scanRecord reads one row selected with sqliteColumns
+func scanRecord(row interface{ Scan(...any) error }) (record, error) {
+	var rec record
+	var code, fingerprint sql.NullString
+	err := row.Scan(&rec.ID, &code, &fingerprint, &rec.Sudoku, &rec.Difficulty, &rec.Size, &rec.Layout, &rec.Created, &rec.Updated)
+	rec.Code, rec.Fingerprint = code.String, fingerprint.String
+	return rec, err
+}
@@ -0,0 +1,202 @@
+package db_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sudoku_gen_go/db"
+	"testing"
+	"time"
+)
+
+// stores opens an empty store of every kind that needs no server
+var stores = []struct {
+	name string
+	open func(t *testing.T) db.Store
+}{
+	{"memory", func(t *testing.T) db.Store { return db.NewMemoryStore() }},
+	{"file", func(t *testing.T) db.Store {
+		store, err := db.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}},
+	{"sqlite", func(t *testing.T) db.Store {
+		store, err := db.OpenSQLite(filepath.Join(t.TempDir(), "sudokus.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	}},
+}
+
+// fill saves every test puzzle, the first created first but at the
+// hardest level, so ordering by created and by difficulty differ
+func fill(t *testing.T, store db.Store) []*db.SudokuRecord {
+	t.Helper()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var recs []*db.SudokuRecord
+	for i, puzzle := range puzzles {
+		rec := testRecord(t, puzzle, len(puzzles)-i)
+		rec.Created = created.Add(time.Duration(i) * time.Minute)
+		id, err := store.Save(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != rec.ID || rec.Code == "" {
+			t.Fatalf("saved as %q with code %q, want %s with a code", id, rec.Code, rec.ID)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+// ids returns the IDs of recs in order
+func ids(recs []*db.SudokuRecord) []string {
+	var out []string
+	for _, rec := range recs {
+		out = append(out, rec.ID)
+	}
+	return out
+}
+
+func TestStoreSaveAndGet(t *testing.T) {
+	for _, s := range stores {
+		store := s.open(t)
+		for _, rec := range fill(t, store) {
+			got, err := store.Get(rec.ID)
+			if err != nil {
+				t.Fatalf("%s: %v", s.name, err)
+			}
+			if got.ID != rec.ID || got.Code != rec.Code || got.Fingerprint != rec.Fingerprint ||
+				got.Difficulty != rec.Difficulty || got.Size != rec.Size || got.Layout != rec.Layout ||
+				!got.Created.Equal(rec.Created) || !reflect.DeepEqual(got.Sudoku, rec.Sudoku) {
+				t.Errorf("%s: stored %+v, got back %+v", s.name, rec, got)
+			}
+			if byCode, err := store.GetByCode(rec.Code); err != nil || byCode.ID != rec.ID {
+				t.Errorf("%s: code %s does not find %s: %v", s.name, rec.Code, rec.ID, err)
+			}
+			if exists, err := store.Exists(rec.ID); !exists || err != nil {
+				t.Errorf("%s: Exists(%s) = %v, %v", s.name, rec.ID, exists, err)
+			}
+		}
+
+		if exists, err := store.Exists("0000000000"); exists || err != nil {
+			t.Errorf("%s: Exists of a missing sudoku = %v, %v", s.name, exists, err)
+		}
+		if _, err := store.Get("0000000000"); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("%s: Get of a missing sudoku: got %v, want ErrNotFound", s.name, err)
+		}
+	}
+}
+
+func TestStoreRejectsDuplicates(t *testing.T) {
+	for _, s := range stores {
+		store := s.open(t)
+		recs := fill(t, store)
+
+		again := testRecord(t, puzzles[0], 3)
+		if _, err := store.Save(again); !errors.Is(err, db.ErrExists) {
+			t.Errorf("%s: saving a stored sudoku: got %v, want ErrExists", s.name, err)
+		}
+
+		// Swapping two digits gives another ID for the same puzzle
+		grid, err := recs[1].Grid().RelabelDigits([]int{2, 1, 3, 4, 5, 6, 7, 8, 9})
+		if err != nil {
+			t.Fatal(err)
+		}
+		isomorph, err := db.NewRecord(grid, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isomorph.ID == recs[1].ID {
+			t.Fatalf("%s: relabeling kept the ID", s.name)
+		}
+		if _, err := store.Save(isomorph); !errors.Is(err, db.ErrDuplicate) {
+			t.Errorf("%s: saving an isomorph: got %v, want ErrDuplicate", s.name, err)
+		}
+		if exists, _ := store.Exists(isomorph.ID); exists {
+			t.Errorf("%s: isomorph was stored", s.name)
+		}
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	for _, s := range stores {
+		store := s.open(t)
+		recs := fill(t, store)
+
+		if err := store.Delete(recs[2].ID); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if exists, err := store.Exists(recs[2].ID); exists || err != nil {
+			t.Errorf("%s: Exists after Delete = %v, %v", s.name, exists, err)
+		}
+		if _, err := store.Get(recs[2].ID); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("%s: Get after Delete: got %v, want ErrNotFound", s.name, err)
+		}
+		if err := store.Delete(recs[2].ID); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("%s: deleting twice: got %v, want ErrNotFound", s.name, err)
+		}
+		left, err := store.List(db.ListOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if len(left) != len(recs)-1 {
+			t.Errorf("%s: %d sudokus left, want %d", s.name, len(left), len(recs)-1)
+		}
+
+		// The puzzle can be stored again once deleted
+		if _, err := store.Save(testRecord(t, puzzles[2], 3)); err != nil {
+			t.Errorf("%s: saving a deleted sudoku again: %v", s.name, err)
+		}
+	}
+}
+
+func TestStoreListPagesAndSorts(t *testing.T) {
+	for _, s := range stores {
+		store := s.open(t)
+		recs := fill(t, store)
+		created := ids(recs)
+		easiest := ids([]*db.SudokuRecord{recs[4], recs[3], recs[2], recs[1], recs[0]})
+
+		for _, tc := range []struct {
+			name string
+			opts db.ListOptions
+			want []string
+		}{
+			{"default", db.ListOptions{}, created},
+			{"created", db.ListOptions{Sort: "created"}, created},
+			{"created desc", db.ListOptions{Sort: "created", Desc: true}, easiest},
+			{"difficulty", db.ListOptions{Sort: "difficulty"}, easiest},
+			{"difficulty desc", db.ListOptions{Sort: "difficulty", Desc: true}, created},
+			{"first page", db.ListOptions{Sort: "difficulty", PerPage: 2}, easiest[:2]},
+			{"second page", db.ListOptions{Sort: "difficulty", Page: 2, PerPage: 2}, easiest[2:4]},
+			{"last page", db.ListOptions{Sort: "difficulty", Page: 3, PerPage: 2}, easiest[4:]},
+			{"past the end", db.ListOptions{Page: 4, PerPage: 2}, nil},
+			{"filtered", db.ListOptions{Difficulty: recs[3].Difficulty}, created[3:4]},
+		} {
+			got, err := store.List(tc.opts)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", s.name, tc.name, err)
+			}
+			if !reflect.DeepEqual(ids(got), tc.want) {
+				t.Errorf("%s, %s: listed %v, want %v", s.name, tc.name, ids(got), tc.want)
+			}
+		}
+
+		for _, field := range db.SortFields {
+			got, err := store.List(db.ListOptions{Sort: field})
+			if err != nil || len(got) != len(recs) {
+				t.Errorf("%s: sorting by %s listed %d sudokus: %v", s.name, field, len(got), err)
+			}
+		}
+		for _, field := range []string{"sudoku", "fingerprint", "created DESC", "-created"} {
+			if _, err := store.List(db.ListOptions{Sort: field}); err == nil {
+				t.Errorf("%s: sorted by unknown field %q", s.name, field)
+			}
+		}
+	}
+}
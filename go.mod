@@ -4,16 +4,28 @@ go 1.22.0
 
 toolchain go1.23.4
 
-require github.com/habibrosyad/pocketbase-go-sdk v0.0.0-20241227100454-1be71768920c
+require (
+	github.com/habibrosyad/pocketbase-go-sdk v0.0.0-20241227100454-1be71768920c
+	github.com/joho/godotenv v1.5.1
+	modernc.org/sqlite v1.34.5
+)
 
 require (
 	github.com/SierraSoftworks/multicast/v2 v2.0.0 // indirect
 	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
 	github.com/donovanhide/eventsource v0.0.0-20210830082556-c59027999da0 // indirect
 	github.com/duke-git/lancet/v2 v2.3.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/go-resty/resty/v2 v2.12.0 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
 )
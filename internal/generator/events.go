@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// EventKind identifies a step of puzzle generation
+type EventKind int
+
+const (
+	// AttemptStarted is sent when a worker begins a new attempt
+	AttemptStarted EventKind = iota
+	// RegionsProgress reports the jigsaw region builder's progress
+	RegionsProgress
+	// RegionsBuilt is sent once the attempt has its region layout
+	RegionsBuilt
+	// Solved is sent once the attempt filled its grid with a solution
+	Solved
+	// CagesBuilt is sent once a killer attempt split its solution into
+	// cages
+	CagesBuilt
+	// CluesRemoved is sent once digging left a unique puzzle
+	CluesRemoved
+	// AttemptFailed is sent when an attempt gives up, see Event.Err
+	AttemptFailed
+	// Generated is sent once with the winning attempt
+	Generated
+)
+
+var eventNames = map[EventKind]string{
+	AttemptStarted:  "attempt started",
+	RegionsProgress: "regions progress",
+	RegionsBuilt:    "regions built",
+	Solved:          "solved",
+	CagesBuilt:      "cages built",
+	CluesRemoved:    "clues removed",
+	AttemptFailed:   "attempt failed",
+	Generated:       "generated",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("event(%d)", int(k))
+}
+
+var (
+	// ErrRegions means no jigsaw region layout could be built
+	ErrRegions = errors.New("failed to generate valid jigsaw regions")
+	// ErrCages means no killer cage split of the solution could be found
+	ErrCages = errors.New("failed to generate valid killer cages")
+	// ErrUnfillable means the region layout could not be filled in time
+	ErrUnfillable = errors.New("failed to fill grid")
+	// ErrOutOfBand means the puzzle missed the target difficulty band
+	ErrOutOfBand = errors.New("puzzle outside target difficulty band")
+	// ErrDuplicate means the puzzle is an isomorph of a known one
+	ErrDuplicate = errors.New("puzzle duplicates a known puzzle")
+	// ErrClueBounds means the puzzle ended with a clue count outside the
+	// bounds set with SetClueBounds
+	ErrClueBounds = errors.New("clue count outside bounds")
+)
+
+// Event describes progress of a Generate call
+type Event struct {
+	Kind EventKind
+	// Worker is the goroutine that ran the attempt
+	Worker int
+	// Attempt is the zero-based attempt number, Attempts the retry budget
+	Attempt  int
+	Attempts int
+	// Percent is set for RegionsProgress
+	Percent int
+	// Clues is the number of givens left, set for CluesRemoved and Generated
+	Clues int
+	// Elapsed is the time since Generate was called
+	Elapsed time.Duration
+	// Err is the reason for AttemptFailed
+	Err error
+}
+
+// SetProgress registers a callback for generation events. Calls are
+// serialized, so fn does not need to be safe for concurrent use, but it runs
+// on the worker goroutines and should return quickly.
+func (g *ClassicGenerator) SetProgress(fn func(Event)) {
+	g.progress = fn
+}
+
+// SetLogger makes the generator log its events to logger. Generated is
+// logged at info level, everything else at debug level.
+func (g *ClassicGenerator) SetLogger(logger *slog.Logger) {
+	g.logger = logger
+}
+
+// emit delivers an event to the progress callback and the logger
+func (g *ClassicGenerator) emit(e Event) {
+	if g.progress == nil && g.logger == nil {
+		return
+	}
+
+	g.eventMu.Lock()
+	defer g.eventMu.Unlock()
+
+	if g.progress != nil {
+		g.progress(e)
+	}
+	if g.logger != nil {
+		level := slog.LevelDebug
+		if e.Kind == Generated {
+			level = slog.LevelInfo
+		}
+		attrs := []slog.Attr{
+			slog.Int("worker", e.Worker),
+			slog.Int("attempt", e.Attempt+1),
+			slog.Int("attempts", e.Attempts),
+			slog.Duration("elapsed", e.Elapsed),
+		}
+		switch e.Kind {
+		case RegionsProgress:
+			attrs = append(attrs, slog.Int("percent", e.Percent))
+		case CluesRemoved, Generated:
+			attrs = append(attrs, slog.Int("clues", e.Clues))
+		case AttemptFailed:
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		g.logger.LogAttrs(context.Background(), level, e.Kind.String(), attrs...)
+	}
+}
@@ -1,18 +1,38 @@
 package generator
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"log/slog"
 	"math/rand"
+	"slices"
+	"sudoku_gen_go/internal/grader"
+	"sudoku_gen_go/internal/solver"
 	"sudoku_gen_go/internal/types"
 	"sync"
 	"time"
 )
 
+const (
+	// fillNodesPerCell bounds a single try at filling an empty grid,
+	// relative to its cell count
+	fillNodesPerCell = 10
+	// fillRestarts is how often filling is retried before a region layout
+	// is given up on
+	fillRestarts = 20
+	// killerNodesPerCell bounds each uniqueness check while digging a
+	// killer puzzle, relative to its cell count
+	killerNodesPerCell = 4
+	// killerStalls is how many checks in a row may hit that bound before
+	// digging stops and the remaining clues stay
+	killerStalls = 3
+)
+
 // SudokuGenerator interface defines methods for generating Sudoku puzzles
 type SudokuGenerator interface {
 	Generate() (*types.Grid, error)
+	GenerateContext(ctx context.Context) (*types.Grid, error)
 	SetDifficulty(level int) error
 }
 
@@ -20,19 +40,49 @@ type SudokuGenerator interface {
 type ClassicGenerator struct {
 	difficulty int
 	size       int
+	boxWidth   int
+	boxHeight  int
 	sudokuType types.SudokuType
+	variants   []types.SudokuType
 	threads    int
 	maxRetries int // Add this field
+	band       *grader.Band
+	symmetry   Symmetry
+	minimal    bool
+	minClues   int
+	maxClues   int // 0 means no bounds
+	minCage    int
+	maxCage    int // 0 means the defaults of cageSizes
+	solver     solver.Solver
+	seed       *int64
+	progress   func(Event)
+	logger     *slog.Logger
+	eventMu    sync.Mutex
+	known      func(fingerprint string) bool
+	seen       map[string]bool // fingerprints of puzzles already returned
+	seenMu     sync.Mutex
 }
 
+// NewClassicGenerator returns a generator for size x size grids with the
+// default boxes of types.BoxDimensions; SetBoxDimensions picks others.
+// A variant such as types.X as typ means that variant on a normal layout.
 func NewClassicGenerator(size int, typ types.SudokuType) *ClassicGenerator {
-	return &ClassicGenerator{
+	boxWidth, boxHeight, _ := types.BoxDimensions(size)
+	g := &ClassicGenerator{
 		difficulty: 1,
 		size:       size,
+		boxWidth:   boxWidth,
+		boxHeight:  boxHeight,
 		sudokuType: typ,
 		threads:    4,    // Default threads
 		maxRetries: 1000, // Default max retries
+		symmetry:   SymmetryNone,
+		solver:     solver.Default,
+	}
+	if typ.IsVariant() {
+		g.sudokuType, g.variants = types.Normal, []types.SudokuType{typ}
 	}
+	return g
 }
 
 func (g *ClassicGenerator) SetThreads(threads int) {
@@ -44,206 +94,405 @@ func (g *ClassicGenerator) SetMaxRetries(retries int) {
 	g.maxRetries = retries
 }
 
-// Generate implements the backtracking algorithm with MRV
+// SetBoxDimensions sets the box shape of normal layouts, boxWidth columns
+// by boxHeight rows, which must tile the grid
+func (g *ClassicGenerator) SetBoxDimensions(boxWidth, boxHeight int) error {
+	if err := types.CheckBoxes(g.size, boxWidth, boxHeight); err != nil {
+		return err
+	}
+	g.boxWidth, g.boxHeight = boxWidth, boxHeight
+	return nil
+}
+
+// SetVariants replaces the variants whose rules the puzzles follow on top
+// of their layout
+func (g *ClassicGenerator) SetVariants(variants ...types.SudokuType) error {
+	for _, v := range variants {
+		if !v.IsVariant() {
+			return fmt.Errorf("unknown variant %q", v)
+		}
+	}
+	g.variants = slices.Clone(variants)
+	return nil
+}
+
+// SetSeed makes generation reproducible: the same seed, size, type and
+// difficulty settings always give the same puzzle. Without a seed a new
+// one is picked for every Generate call and stored in the result.
+func (g *ClassicGenerator) SetSeed(seed int64) {
+	g.seed = &seed
+}
+
+// SetSolver selects the backend used to fill grids and check uniqueness
+func (g *ClassicGenerator) SetSolver(s solver.Solver) {
+	g.solver = s
+}
+
+// SetTargetDifficulty makes the generator keep producing candidates until
+// the hardest technique a puzzle needs falls within band. It replaces the
+// removal percentage derived from SetDifficulty.
+func (g *ClassicGenerator) SetTargetDifficulty(band grader.Band) error {
+	if band.Min > band.Max {
+		return fmt.Errorf("invalid difficulty band %v", band)
+	}
+	g.band = &band
+	return nil
+}
+
+// SetClueBounds asks for puzzles with between minClues and maxClues
+// givens; equal bounds ask for an exact count. Digging aims inside the
+// bounds whatever the difficulty level, and attempts that end outside
+// them fail with ErrClueBounds.
+func (g *ClassicGenerator) SetClueBounds(minClues, maxClues int) error {
+	cells := g.size * g.size
+	if minClues < 0 || maxClues < 1 || minClues > maxClues || maxClues > cells {
+		return fmt.Errorf("invalid clue bounds %d to %d for a %dx%d grid", minClues, maxClues, g.size, g.size)
+	}
+	g.minClues, g.maxClues = minClues, maxClues
+	return nil
+}
+
+// SetMinimal makes the generator return only minimal puzzles, where
+// removing any single clue would allow a second solution. It digs as far
+// as uniqueness allows whatever the difficulty level, then removes single
+// clues, so the final layout may break the symmetry set with SetSymmetry.
+func (g *ClassicGenerator) SetMinimal(minimal bool) {
+	g.minimal = minimal
+}
+
+// SetKnown rejects candidates whose isomorph fingerprint known reports, for
+// example because a store already holds an equivalent puzzle. known is
+// called from every worker, so it must be safe for concurrent use. The
+// generator also never returns two isomorphs itself. Puzzles without a
+// canonical form, such as jigsaw and variant puzzles, are not checked.
+func (g *ClassicGenerator) SetKnown(known func(fingerprint string) bool) {
+	g.known = known
+}
+
+// Generate is GenerateContext without cancellation or deadline
 func (g *ClassicGenerator) Generate() (*types.Grid, error) {
-	startTime := time.Now()
-	maxTime := time.Duration(g.getMaxGenerationTime()) * time.Millisecond
-	resultChan := make(chan *types.Grid)
-	errorChan := make(chan error, 1)
-	stopChan := make(chan struct{})
-	var wg sync.WaitGroup
+	return g.GenerateContext(context.Background())
+}
 
-	// Launch worker goroutines
-	attemptsPerThread := g.maxRetries / g.threads
-	if attemptsPerThread < 1 {
-		attemptsPerThread = 1
+// GenerateContext fills a fresh grid with the constraint-propagating solver
+// and digs it down to a unique puzzle. Cancelling ctx or reaching its
+// deadline stops every worker, and the returned error wraps ctx.Err().
+//
+// Attempts are numbered globally and each one draws from its own random
+// source derived from the seed. Workers pick attempts in order and the
+// lowest successful attempt wins, so a given seed produces the same puzzle
+// regardless of the number of threads.
+func (g *ClassicGenerator) GenerateContext(ctx context.Context) (*types.Grid, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	seed := g.nextSeed()
+	start := time.Now()
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		next    int
+		best    = g.maxRetries
+		result  *types.Grid
+		lastErr error
+		running = make(map[int]context.CancelFunc)
+	)
+
+	// claim hands out the next attempt, or false once no attempt can beat
+	// the best result so far
+	claim := func() (int, context.Context, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if next >= best || workCtx.Err() != nil {
+			return 0, nil, false
+		}
+		attempt := next
+		next++
+		attemptCtx, attemptCancel := context.WithCancel(workCtx)
+		running[attempt] = attemptCancel
+		return attempt, attemptCtx, true
+	}
+
+	// finish records the outcome of an attempt and stops any running
+	// attempt that can no longer win
+	finish := func(attempt int, grid *types.Grid, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		running[attempt]()
+		delete(running, attempt)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			lastErr = err
+		}
+		if grid == nil || attempt >= best {
+			return
+		}
+		best, result = attempt, grid
+		for other, attemptCancel := range running {
+			if other > attempt {
+				attemptCancel()
+			}
+		}
 	}
 
+	// Launch worker goroutines
 	for i := 0; i < g.threads; i++ {
 		wg.Add(1)
 		go func(threadID int) {
 			defer wg.Done()
-			for attempt := 0; attempt < attemptsPerThread; attempt++ {
-				select {
-				case <-stopChan:
+			for {
+				attempt, attemptCtx, ok := claim()
+				if !ok {
 					return
-				default:
 				}
 
-				fmt.Printf("Thread %d: Attempt %d/%d\n", threadID, attempt+1, attemptsPerThread)
-
-				grid := types.NewGrid(g.size, g.sudokuType)
-				var err error
-
-				if g.sudokuType == types.Jigsaw {
-					grid.SubGrids, err = g.generateJigsawRegionsSerial()
-					if err != nil {
-						continue
-					}
-				} else {
-					grid.SubGrids = g.generateNormalSubgrids()
+				report := func(e Event) {
+					e.Worker, e.Attempt, e.Attempts = threadID, attempt, g.maxRetries
+					e.Elapsed = time.Since(start)
+					g.emit(e)
 				}
+				report(Event{Kind: AttemptStarted})
 
-				if solved := g.solve(grid, startTime, maxTime); solved {
-					// Copy solution
-					grid.Solution = make([][]int, g.size)
-					for i := range grid.Puzzle {
-						grid.Solution[i] = make([]int, g.size)
-						copy(grid.Solution[i], grid.Puzzle[i])
-					}
-
-					// Remove numbers based on difficulty
-					g.removeNumbers(grid)
-
-					select {
-					case resultChan <- grid:
-						close(stopChan) // Signal other goroutines to stop
-						return
-					case <-stopChan:
-						return
-					}
+				rng := rand.New(rand.NewSource(attemptSeed(seed, attempt)))
+				grid, err := g.attempt(attemptCtx, rng, report)
+				if err == nil {
+					err = attemptCtx.Err()
+				}
+				if err != nil {
+					report(Event{Kind: AttemptFailed, Err: err})
+					grid = nil
 				}
+				finish(attempt, grid, err)
 			}
 		}(i)
 	}
+	wg.Wait()
 
-	// Start a goroutine to close channels after all workers are done
-	go func() {
-		wg.Wait()
-		select {
-		case <-stopChan:
-			// Already closed due to success
-		default:
-			errorChan <- fmt.Errorf("failed to generate valid puzzle after %d attempts", g.maxRetries)
-		}
-		close(resultChan)
-	}()
-
-	// Wait for result or timeout
-	select {
-	case grid, ok := <-resultChan:
-		if !ok {
-			// Channel closed without result
-			err := <-errorChan
-			return nil, err
+	if result == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("generation stopped: %w", err)
+		}
+		if lastErr != nil {
+			return nil, fmt.Errorf("failed to generate valid puzzle after %d attempts: %w", g.maxRetries, lastErr)
+		}
+		return nil, fmt.Errorf("failed to generate valid puzzle after %d attempts", g.maxRetries)
+	}
+	result.Seed = seed
+	if fp, err := result.Fingerprint(); err == nil {
+		g.seenMu.Lock()
+		if g.seen == nil {
+			g.seen = make(map[string]bool)
 		}
-		fmt.Println("Successfully generated puzzle")
-		return grid, nil
-	case <-time.After(maxTime):
-		close(stopChan) // Signal all goroutines to stop
-		wg.Wait()       // Wait for all goroutines to finish
-		return nil, fmt.Errorf("generation timed out after %v", maxTime)
+		g.seen[fp] = true
+		g.seenMu.Unlock()
 	}
+	g.emit(Event{
+		Kind:     Generated,
+		Attempt:  best,
+		Attempts: g.maxRetries,
+		Clues:    countClues(result),
+		Elapsed:  time.Since(start),
+	})
+	return result, nil
 }
 
-// Remove the parallel region generation since we're now parallelizing the whole generation
-func (g *ClassicGenerator) generateJigsawRegions() ([][]int, error) {
-	return g.generateJigsawRegionsSerial()
+// Check reports settings no attempt could generate a puzzle for, such as
+// boxes that do not tile the grid, variants that do not fit it or a solver
+// that cannot handle the variants' rules
+func (g *ClassicGenerator) Check() error {
+	if g.sudokuType != types.Jigsaw {
+		if err := types.CheckBoxes(g.size, g.boxWidth, g.boxHeight); err != nil {
+			return err
+		}
+	} else if g.size < 2 {
+		return fmt.Errorf("invalid size %d", g.size)
+	}
+	grid := types.NewGridWithBoxes(g.size, g.boxWidth, g.boxHeight, g.sudokuType)
+	grid.Variants = g.variants
+	if err := grid.CheckVariants(); err != nil {
+		return err
+	}
+
+	if g.isKiller() {
+		// Cages only show up once a grid is filled, so try the solver on
+		// a single cell cage up front
+		probe := types.NewGridWithBoxes(1, 1, 1, types.Normal)
+		probe.Variants = []types.SudokuType{types.Killer}
+		probe.Cages = []types.Cage{{Cells: []int{0}, Sum: 1}}
+		if _, err := g.solver.CountSolutions(probe, 1, solver.Options{}); errors.Is(err, solver.ErrUnsupported) {
+			return fmt.Errorf("killer cages need a solver that checks sums: %w", err)
+		}
+	}
+	return nil
 }
 
-func (g *ClassicGenerator) solve(grid *types.Grid, startTime time.Time, maxTime time.Duration) bool {
-	if time.Since(startTime) > maxTime {
-		return false
+// attempt builds one candidate puzzle, reporting its progress. It returns
+// an error if the attempt failed or was cancelled.
+func (g *ClassicGenerator) attempt(ctx context.Context, rng *rand.Rand, report func(Event)) (*types.Grid, error) {
+	grid := types.NewGridWithBoxes(g.size, g.boxWidth, g.boxHeight, g.sudokuType)
+	grid.Variants = slices.Clone(g.variants)
+
+	if g.sudokuType == types.Jigsaw {
+		regions, err := g.generateJigsawRegionsSerial(ctx, rng, report)
+		if err != nil {
+			return nil, err
+		}
+		grid.SubGrids = regions
+	} else {
+		grid.SubGrids = g.generateNormalSubgrids()
 	}
+	report(Event{Kind: RegionsBuilt})
 
-	pos := g.findEmptyPositionWithMRV(grid)
-	if pos == nil {
-		return true
+	if err := g.fill(ctx, rng, grid); err != nil {
+		return nil, err
 	}
+	report(Event{Kind: Solved})
 
-	row, col := pos[0], pos[1]
-	nums := g.getShuffledNumbers()
+	if g.isKiller() {
+		cages, err := g.generateCages(ctx, rng, grid.Solution)
+		if err != nil {
+			return nil, err
+		}
+		grid.Cages = cages
+		report(Event{Kind: CagesBuilt})
+	}
+
+	// Remove numbers based on difficulty, keeping the solution unique
+	if err := g.removeNumbers(ctx, rng, grid); err != nil {
+		return nil, err
+	}
+	report(Event{Kind: CluesRemoved, Clues: countClues(grid)})
 
-	for _, num := range nums {
-		if g.isValid(grid, num, row, col) {
-			grid.Puzzle[row][col] = num
-			if g.solve(grid, startTime, maxTime) {
-				return true
+	if g.band != nil && !g.reshapeToBand(ctx, rng, grid) {
+		return nil, ErrOutOfBand
+	}
+	if g.minimal {
+		if err := g.minimize(ctx, rng, grid); err != nil {
+			return nil, err
+		}
+		// Removing clues can only make the puzzle harder
+		if g.band != nil {
+			result, err := grader.Grade(grid)
+			if err != nil || !g.band.Contains(result.Hardest) {
+				return nil, ErrOutOfBand
 			}
-			grid.Puzzle[row][col] = 0
 		}
 	}
-
-	return false
+	if clues := countClues(grid); g.maxClues > 0 && (clues < g.minClues || clues > g.maxClues) {
+		if g.minClues == g.maxClues {
+			return nil, fmt.Errorf("%w: got %d, want %d", ErrClueBounds, clues, g.minClues)
+		}
+		return nil, fmt.Errorf("%w: got %d, want %d to %d", ErrClueBounds, clues, g.minClues, g.maxClues)
+	}
+	if g.isDuplicate(grid) {
+		return nil, ErrDuplicate
+	}
+	return grid, nil
 }
 
-func (g *ClassicGenerator) findEmptyPositionWithMRV(grid *types.Grid) []int {
-	minPossibilities := g.size + 1
-	var bestPos []int
+// isDuplicate reports whether an isomorph of grid was already generated or
+// is known to the caller
+func (g *ClassicGenerator) isDuplicate(grid *types.Grid) bool {
+	fp, err := grid.Fingerprint()
+	if err != nil {
+		return false
+	}
+	g.seenMu.Lock()
+	seen := g.seen[fp]
+	g.seenMu.Unlock()
+	return seen || (g.known != nil && g.known(fp))
+}
 
-	for i := 0; i < g.size; i++ {
-		for j := 0; j < g.size; j++ {
-			if grid.Puzzle[i][j] == 0 {
-				count := 0
-				for num := 1; num <= g.size; num++ {
-					if g.isValid(grid, num, i, j) {
-						count++
-					}
-				}
-				if count < minPossibilities {
-					minPossibilities = count
-					bestPos = []int{i, j}
-				}
+// countClues returns the number of givens in the puzzle
+func countClues(grid *types.Grid) int {
+	clues := 0
+	for _, row := range grid.Puzzle {
+		for _, num := range row {
+			if num != 0 {
+				clues++
 			}
 		}
 	}
+	return clues
+}
 
-	return bestPos
+// nextSeed returns the configured seed, or a fresh one if none was set
+func (g *ClassicGenerator) nextSeed() int64 {
+	if g.seed != nil {
+		return *g.seed
+	}
+	return time.Now().UnixNano()
 }
 
-func (g *ClassicGenerator) isValid(grid *types.Grid, num, row, col int) bool {
-	// Check row
-	for x := 0; x < g.size; x++ {
-		if grid.Puzzle[row][x] == num {
-			return false
-		}
+// attemptSeed derives the seed of one attempt. Attempt 0 uses the seed as
+// is, later attempts mix in their number with a splitmix64 step.
+func attemptSeed(seed int64, attempt int) int64 {
+	if attempt == 0 {
+		return seed
 	}
+	z := uint64(seed) + uint64(attempt)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
 
-	// Check column
-	for x := 0; x < g.size; x++ {
-		if grid.Puzzle[x][col] == num {
-			return false
-		}
+// fill completes the empty grid with a random solution and stores a copy of
+// it in grid.Solution. Filling an empty board has a heavy-tailed running
+// time, so each try is cut off after a small number of search nodes and
+// restarted with a fresh digit order. It returns ErrUnfillable when every
+// try runs out of nodes, and ctx's error if it was cancelled.
+func (g *ClassicGenerator) fill(ctx context.Context, rng *rand.Rand, grid *types.Grid) error {
+	opts := solver.Options{
+		Shuffle: func(digits []int) {
+			rng.Shuffle(len(digits), func(i, j int) {
+				digits[i], digits[j] = digits[j], digits[i]
+			})
+		},
+		Stop:      stopped(ctx),
+		NodeLimit: g.size * g.size * fillNodesPerCell,
 	}
 
-	// Check subgrid
-	cellIndex := row*g.size + col
-	regionIndex := g.findRegionIndex(grid, cellIndex)
+	for restart := 0; restart < fillRestarts; restart++ {
+		solution, err := g.solver.Solve(grid, opts)
+		if errors.Is(err, solver.ErrNodeLimit) {
+			continue
+		}
+		if err != nil {
+			return solverErr(ctx, err)
+		}
 
-	for _, idx := range grid.SubGrids[regionIndex] {
-		r, c := idx/g.size, idx%g.size
-		if grid.Puzzle[r][c] == num {
-			return false
+		grid.Puzzle = solution
+		grid.Solution = make([][]int, g.size)
+		for i := range solution {
+			grid.Solution[i] = make([]int, g.size)
+			copy(grid.Solution[i], solution[i])
 		}
+		return nil
 	}
-
-	return true
+	return ErrUnfillable
 }
 
-func (g *ClassicGenerator) getShuffledNumbers() []int {
-	nums := make([]int, g.size)
-	for i := range nums {
-		nums[i] = i + 1
+// solverErr returns ctx's error when the solver stopped for it, and the
+// solver's error otherwise
+func solverErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
 	}
-	rand.Shuffle(len(nums), func(i, j int) {
-		nums[i], nums[j] = nums[j], nums[i]
-	})
-	return nums
+	return err
 }
 
-func (g *ClassicGenerator) getMaxGenerationTime() int {
-	if g.sudokuType == types.Jigsaw {
-		return max(15000, g.size*g.size*50)
+// stopped turns a context into a solver poll function
+func stopped(ctx context.Context) func() bool {
+	return func() bool {
+		return ctx.Err() != nil
 	}
-	return 5000
 }
 
-// Helper functions
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
+// Remove the parallel region generation since we're now parallelizing the whole generation
+func (g *ClassicGenerator) generateJigsawRegions(ctx context.Context, rng *rand.Rand, report func(Event)) ([][]int, error) {
+	return g.generateJigsawRegionsSerial(ctx, rng, report)
 }
 
 func (g *ClassicGenerator) SetDifficulty(level int) error {
@@ -255,65 +504,23 @@ func (g *ClassicGenerator) SetDifficulty(level int) error {
 }
 
 func (g *ClassicGenerator) generateNormalSubgrids() [][]int {
-	size := g.size
-	boxSize := int(math.Sqrt(float64(size)))
-	// Handle non-square sizes (like 12)
-	if boxSize*boxSize != size {
-		boxWidth := 3  // Default for size 12
-		boxHeight := 4 // Default for size 12
-		return g.generateRectangularSubgrids(boxWidth, boxHeight)
-	}
-
-	regions := make([][]int, size)
-
-	for boxRow := 0; boxRow < boxSize; boxRow++ {
-		for boxCol := 0; boxCol < boxSize; boxCol++ {
-			region := make([]int, 0, g.size)
-			for i := 0; i < boxSize; i++ {
-				for j := 0; j < boxSize; j++ {
-					row := boxRow*boxSize + i
-					col := boxCol*boxSize + j
-					region = append(region, row*g.size+col)
-				}
-			}
-			regions[boxRow*boxSize+boxCol] = region
-		}
-	}
-
-	return regions
-}
-
-func (g *ClassicGenerator) generateRectangularSubgrids(boxWidth, boxHeight int) [][]int {
-	size := g.size
-	regions := make([][]int, size)
-
-	for boxRow := 0; boxRow < size/boxHeight; boxRow++ {
-		for boxCol := 0; boxCol < size/boxWidth; boxCol++ {
-			region := make([]int, 0, size)
-			for i := 0; i < boxHeight; i++ {
-				for j := 0; j < boxWidth; j++ {
-					row := boxRow*boxHeight + i
-					col := boxCol*boxWidth + j
-					region = append(region, row*size+col)
-				}
-			}
-			regions[boxRow*(size/boxWidth)+boxCol] = region
-		}
-	}
-
-	return regions
+	return types.BoxRegions(g.size, g.boxWidth, g.boxHeight)
 }
 
-func (g *ClassicGenerator) generateJigsawRegionsSerial() ([][]int, error) {
+func (g *ClassicGenerator) generateJigsawRegionsSerial(ctx context.Context, rng *rand.Rand, report func(Event)) ([][]int, error) {
 	maxAttempts := 1000000
 	size := g.size
 	lastProgress := 0
 
 	for attempts := 0; attempts < maxAttempts; attempts++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Show progress every 10%
 		progress := attempts * 100 / maxAttempts
 		if progress/10 > lastProgress/10 {
-			fmt.Printf("Generating jigsaw regions... %d%%\n", progress)
+			report(Event{Kind: RegionsProgress, Percent: progress})
 			lastProgress = progress
 		}
 
@@ -336,7 +543,7 @@ func (g *ClassicGenerator) generateJigsawRegionsSerial() ([][]int, error) {
 			}
 
 			// Start with a random available cell
-			start := available[rand.Intn(len(available))]
+			start := available[rng.Intn(len(available))]
 			region := []int{start}
 			used[start] = true
 
@@ -356,7 +563,7 @@ func (g *ClassicGenerator) generateJigsawRegionsSerial() ([][]int, error) {
 					break
 				}
 
-				next := candidates[rand.Intn(len(candidates))]
+				next := candidates[rng.Intn(len(candidates))]
 				region = append(region, next)
 				used[next] = true
 			}
@@ -373,7 +580,7 @@ func (g *ClassicGenerator) generateJigsawRegionsSerial() ([][]int, error) {
 		}
 	}
 
-	return nil, errors.New("failed to generate valid jigsaw regions")
+	return nil, ErrRegions
 }
 
 func (g *ClassicGenerator) buildAdjacencyList() [][]int {
@@ -401,34 +608,136 @@ func (g *ClassicGenerator) buildAdjacencyList() [][]int {
 	return adjacency
 }
 
-func (g *ClassicGenerator) findRegionIndex(grid *types.Grid, cellIndex int) int {
-	for i, region := range grid.SubGrids {
-		for _, cell := range region {
-			if cell == cellIndex {
-				return i
-			}
-		}
-	}
-	return -1
-}
-
-func (g *ClassicGenerator) removeNumbers(grid *types.Grid) {
-	cells := make([]int, g.size*g.size)
-	for i := range cells {
-		cells[i] = i
-	}
+// removeNumbers digs holes one symmetry orbit at a time and rolls back any
+// removal that would allow more than one solution. It returns the solver's
+// error, or ctx's if it was cancelled while digging.
+func (g *ClassicGenerator) removeNumbers(ctx context.Context, rng *rand.Rand, grid *types.Grid) error {
+	orbits := g.orbits()
 
-	rand.Shuffle(len(cells), func(i, j int) {
-		cells[i], cells[j] = cells[j], cells[i]
+	rng.Shuffle(len(orbits), func(i, j int) {
+		orbits[i], orbits[j] = orbits[j], orbits[i]
 	})
 
 	// Calculate cells to remove based on difficulty (1-5)
 	// Difficulty 1: 30%, 2: 40%, 3: 50%, 4: 60%, 5: 70%
 	cellsToRemove := (g.difficulty*10 + 20) * g.size * g.size / 100
+	if g.band != nil || g.minimal || g.isKiller() {
+		// Dig as far as uniqueness allows, reshapeToBand adds clues back.
+		// Killer puzzles lean on their cage sums instead of givens.
+		cellsToRemove = g.size * g.size
+	}
+	if g.maxClues > 0 {
+		// Remove enough to reach maxClues but never go below minClues
+		cells := g.size * g.size
+		cellsToRemove = min(max(cellsToRemove, cells-g.maxClues), cells-g.minClues)
+	}
+
+	opts := solver.Options{Stop: stopped(ctx)}
+	if g.isKiller() {
+		// With few givens left, proving a killer puzzle unique can take
+		// very long; a clue too costly to check stays
+		opts.NodeLimit = g.size * g.size * killerNodesPerCell
+	}
+
+	removed, stalls := 0, 0
+	for _, orbit := range orbits {
+		if removed >= cellsToRemove || stalls == killerStalls {
+			break
+		}
+		if g.maxClues > 0 && removed+len(orbit) > cellsToRemove {
+			// A smaller orbit may still fit
+			continue
+		}
+
+		for _, cellIdx := range orbit {
+			grid.Puzzle[cellIdx/g.size][cellIdx%g.size] = 0
+		}
 
-	for i := 0; i < cellsToRemove; i++ {
-		cellIdx := cells[i]
+		count, err := g.solver.CountSolutions(grid, 2, opts)
+		if errors.Is(err, solver.ErrNodeLimit) {
+			stalls++
+		} else if err != nil {
+			return solverErr(ctx, err)
+		} else {
+			stalls = 0
+		}
+		if count != 1 || err != nil {
+			// A second solution appeared or uniqueness is too costly to
+			// prove, put the clues back
+			for _, cellIdx := range orbit {
+				row, col := cellIdx/g.size, cellIdx%g.size
+				grid.Puzzle[row][col] = grid.Solution[row][col]
+			}
+			continue
+		}
+		removed += len(orbit)
+	}
+
+	return nil
+}
+
+// minimize removes every clue that is not needed for uniqueness, one cell
+// at a time in random order. A clue that is needed stays needed as others
+// are removed, so a single pass leaves a minimal puzzle. It returns the
+// solver's error, or ctx's if it was cancelled.
+func (g *ClassicGenerator) minimize(ctx context.Context, rng *rand.Rand, grid *types.Grid) error {
+	cells := rng.Perm(g.size * g.size)
+	for _, cellIdx := range cells {
 		row, col := cellIdx/g.size, cellIdx%g.size
+		value := grid.Puzzle[row][col]
+		if value == 0 {
+			continue
+		}
 		grid.Puzzle[row][col] = 0
+
+		count, err := g.solver.CountSolutions(grid, 2, solver.Options{Stop: stopped(ctx)})
+		if err != nil {
+			return solverErr(ctx, err)
+		}
+		if count != 1 {
+			grid.Puzzle[row][col] = value
+		}
+	}
+	return nil
+}
+
+// reshapeToBand grades the dug puzzle and, while it is harder than the
+// target band, gives back clues from the solution in random order. It
+// reports whether the puzzle ended up inside the band.
+func (g *ClassicGenerator) reshapeToBand(ctx context.Context, rng *rand.Rand, grid *types.Grid) bool {
+	result, err := grader.Grade(grid)
+	if err != nil {
+		return false
+	}
+	if result.Hardest < g.band.Min {
+		// Already maximally dug, this solution cannot get any harder
+		return false
+	}
+
+	// Give back whole orbits to keep the symmetry; digging empties them
+	// completely or not at all
+	var empty [][]int
+	for _, orbit := range g.orbits() {
+		if grid.Puzzle[orbit[0]/g.size][orbit[0]%g.size] == 0 {
+			empty = append(empty, orbit)
+		}
+	}
+	rng.Shuffle(len(empty), func(i, j int) {
+		empty[i], empty[j] = empty[j], empty[i]
+	})
+
+	for _, orbit := range empty {
+		if result.Hardest <= g.band.Max || ctx.Err() != nil {
+			break
+		}
+		for _, cellIdx := range orbit {
+			row, col := cellIdx/g.size, cellIdx%g.size
+			grid.Puzzle[row][col] = grid.Solution[row][col]
+		}
+		if result, err = grader.Grade(grid); err != nil {
+			return false
+		}
 	}
+
+	return g.band.Contains(result.Hardest)
 }
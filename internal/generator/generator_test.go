@@ -0,0 +1,146 @@
+package generator_test
+
+import (
+	"errors"
+	"reflect"
+	"sudoku_gen_go/internal/generator"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// generate builds one puzzle, failing the test on error
+func generate(t *testing.T, gen *generator.ClassicGenerator) *types.Grid {
+	t.Helper()
+	grid, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grid
+}
+
+// samePuzzle fails unless both grids hold the same puzzle
+func samePuzzle(t *testing.T, name string, a, b *types.Grid) {
+	t.Helper()
+	if !reflect.DeepEqual(a.Puzzle, b.Puzzle) {
+		t.Errorf("%s: puzzles differ:\n%v\n%v", name, a.Puzzle, b.Puzzle)
+	}
+	if !reflect.DeepEqual(a.Solution, b.Solution) {
+		t.Errorf("%s: solutions differ:\n%v\n%v", name, a.Solution, b.Solution)
+	}
+	if !reflect.DeepEqual(a.SubGrids, b.SubGrids) {
+		t.Errorf("%s: regions differ:\n%v\n%v", name, a.SubGrids, b.SubGrids)
+	}
+}
+
+func TestSeedReproducesPuzzle(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		size       int
+		typ        types.SudokuType
+		difficulty int
+	}{
+		{"9x9", 9, types.Normal, 3},
+		{"6x6 hard", 6, types.Normal, 5},
+		{"jigsaw", 6, types.Jigsaw, 3},
+		{"x", 9, types.X, 2},
+	} {
+		var grids []*types.Grid
+		for _, threads := range []int{1, 4} {
+			gen := generator.NewClassicGenerator(tc.size, tc.typ)
+			gen.SetSeed(42)
+			gen.SetThreads(threads)
+			if err := gen.SetDifficulty(tc.difficulty); err != nil {
+				t.Fatal(err)
+			}
+			grid := generate(t, gen)
+			if grid.Seed != 42 {
+				t.Errorf("%s: stored seed %d, want 42", tc.name, grid.Seed)
+			}
+			grids = append(grids, grid)
+		}
+		samePuzzle(t, tc.name, grids[0], grids[1])
+	}
+}
+
+func TestStoredSeedReproducesPuzzle(t *testing.T) {
+	gen := generator.NewClassicGenerator(9, types.Normal)
+	first := generate(t, gen)
+	if first.Seed == 0 {
+		t.Fatal("no seed stored")
+	}
+
+	again := generator.NewClassicGenerator(9, types.Normal)
+	again.SetSeed(first.Seed)
+	samePuzzle(t, "stored seed", first, generate(t, again))
+}
+
+func TestMinimalPuzzles(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		size int
+		typ  types.SudokuType
+	}{
+		{"9x9", 9, types.Normal},
+		{"jigsaw", 6, types.Jigsaw},
+	} {
+		gen := generator.NewClassicGenerator(tc.size, tc.typ)
+		gen.SetSeed(1)
+		gen.SetMinimal(true)
+		grid := generate(t, gen)
+		if minimal, err := solver.IsMinimal(grid); !minimal || err != nil {
+			t.Errorf("%s: IsMinimal = %v, %v, want true", tc.name, minimal, err)
+		}
+	}
+}
+
+func TestClueBounds(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		size               int
+		typ                types.SudokuType
+		minClues, maxClues int
+	}{
+		{"9x9 range", 9, types.Normal, 28, 32},
+		{"9x9 exact", 9, types.Normal, 30, 30},
+		{"6x6", 6, types.Normal, 10, 14},
+		{"jigsaw", 6, types.Jigsaw, 12, 16},
+	} {
+		gen := generator.NewClassicGenerator(tc.size, tc.typ)
+		gen.SetSeed(7)
+		if err := gen.SetClueBounds(tc.minClues, tc.maxClues); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		grid := generate(t, gen)
+		clues := 0
+		for _, row := range grid.Puzzle {
+			for _, num := range row {
+				if num != 0 {
+					clues++
+				}
+			}
+		}
+		if clues < tc.minClues || clues > tc.maxClues {
+			t.Errorf("%s: %d clues, want %d to %d", tc.name, clues, tc.minClues, tc.maxClues)
+		}
+	}
+}
+
+func TestClueBoundsRejected(t *testing.T) {
+	gen := generator.NewClassicGenerator(9, types.Normal)
+	for _, bounds := range [][2]int{{-1, 30}, {30, 20}, {0, 0}, {20, 82}} {
+		if err := gen.SetClueBounds(bounds[0], bounds[1]); err == nil {
+			t.Errorf("SetClueBounds(%d, %d) accepted invalid bounds", bounds[0], bounds[1])
+		}
+	}
+
+	// No 9x9 puzzle with fewer than 17 givens has a unique solution
+	gen.SetSeed(1)
+	gen.SetMaxRetries(3)
+	if err := gen.SetClueBounds(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.Generate(); !errors.Is(err, generator.ErrClueBounds) {
+		t.Errorf("got %v, want ErrClueBounds", err)
+	}
+}
@@ -0,0 +1,164 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sudoku_gen_go/internal/types"
+)
+
+// maxCageAttempts bounds how often a cage split is started over before an
+// attempt gives up on its solution
+const maxCageAttempts = 1000
+
+// SetCageSizes bounds the number of cells in each killer cage. Without it
+// cages have 2 cells up to half the grid size, at least 3 and at most 6.
+func (g *ClassicGenerator) SetCageSizes(minSize, maxSize int) error {
+	if minSize < 1 || minSize > maxSize || maxSize > g.size {
+		return fmt.Errorf("invalid cage sizes %d to %d for a %dx%d grid", minSize, maxSize, g.size, g.size)
+	}
+	g.minCage, g.maxCage = minSize, maxSize
+	return nil
+}
+
+// cageSizes returns the cage size bounds, applying the defaults
+func (g *ClassicGenerator) cageSizes() (minSize, maxSize int) {
+	if g.maxCage > 0 {
+		return g.minCage, g.maxCage
+	}
+	return min(2, g.size), min(6, max(3, (g.size+1)/2), g.size)
+}
+
+// isKiller reports whether the generator builds killer puzzles
+func (g *ClassicGenerator) isKiller() bool {
+	for _, v := range g.variants {
+		if v == types.Killer {
+			return true
+		}
+	}
+	return false
+}
+
+// generateCages splits the solved grid into killer cages: connected cells
+// with no repeated digit, within the cage size bounds, summed from the
+// solution. Like the jigsaw regions, cages are grown from random cells.
+// Growth starts at the free cell with the fewest free neighbours so few
+// cells get cut off; a cage left too small joins a neighbouring cage, and
+// if none can take it the split starts over.
+func (g *ClassicGenerator) generateCages(ctx context.Context, rng *rand.Rand, solution [][]int) ([]types.Cage, error) {
+	size := g.size
+	minSize, maxSize := g.cageSizes()
+	adjacency := g.buildAdjacencyList()
+	digit := func(cell int) uint64 {
+		return 1 << solution[cell/size][cell%size]
+	}
+
+	for attempts := 0; attempts < maxCageAttempts; attempts++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		cageOf := make([]int, size*size)
+		for i := range cageOf {
+			cageOf[i] = -1
+		}
+		freeNeighbors := func(cell int) int {
+			free := 0
+			for _, neighbor := range adjacency[cell] {
+				if cageOf[neighbor] < 0 {
+					free++
+				}
+			}
+			return free
+		}
+
+		var cages [][]int
+		var digits []uint64
+		valid := true
+		for {
+			start, fewest, ties := -1, 5, 0
+			for cell, c := range cageOf {
+				if c >= 0 {
+					continue
+				}
+				switch free := freeNeighbors(cell); {
+				case free < fewest:
+					start, fewest, ties = cell, free, 1
+				case free == fewest:
+					ties++
+					if rng.Intn(ties) == 0 {
+						start = cell
+					}
+				}
+			}
+			if start < 0 {
+				break
+			}
+
+			id := len(cages)
+			cage := []int{start}
+			mask := digit(start)
+			cageOf[start] = id
+			target := minSize + rng.Intn(maxSize-minSize+1)
+			for len(cage) < target {
+				var candidates []int
+				for _, cell := range cage {
+					for _, neighbor := range adjacency[cell] {
+						if cageOf[neighbor] < 0 && mask&digit(neighbor) == 0 {
+							candidates = append(candidates, neighbor)
+						}
+					}
+				}
+				if len(candidates) == 0 {
+					break
+				}
+				next := candidates[rng.Intn(len(candidates))]
+				cage = append(cage, next)
+				mask |= digit(next)
+				cageOf[next] = id
+			}
+
+			if len(cage) >= minSize {
+				cages = append(cages, cage)
+				digits = append(digits, mask)
+				continue
+			}
+
+			// Too small: merge into a neighbouring cage that has room and
+			// none of its digits
+			into := -1
+			for _, cell := range cage {
+				for _, neighbor := range adjacency[cell] {
+					other := cageOf[neighbor]
+					if other >= 0 && other != id && len(cages[other])+len(cage) <= maxSize && digits[other]&mask == 0 {
+						into = other
+					}
+				}
+			}
+			if into < 0 {
+				valid = false
+				break
+			}
+			for _, cell := range cage {
+				cageOf[cell] = into
+			}
+			cages[into] = append(cages[into], cage...)
+			digits[into] |= mask
+		}
+
+		if !valid {
+			continue
+		}
+		result := make([]types.Cage, len(cages))
+		for i, cells := range cages {
+			sum := 0
+			for _, cell := range cells {
+				sum += solution[cell/size][cell%size]
+			}
+			result[i] = types.Cage{Cells: cells, Sum: sum}
+		}
+		return result, nil
+	}
+
+	return nil, ErrCages
+}
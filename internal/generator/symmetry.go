@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symmetry is the pattern the givens of a puzzle follow. Cells are removed
+// and given back in orbits, the sets of cells the symmetry maps onto each
+// other, so the clue layout keeps the pattern.
+type Symmetry string
+
+const (
+	// SymmetryNone removes cells one at a time in random order
+	SymmetryNone Symmetry = "none"
+	// SymmetryRotational180 keeps the layout under a half turn
+	SymmetryRotational180 Symmetry = "rotational180"
+	// SymmetryRotational90 keeps the layout under a quarter turn
+	SymmetryRotational90 Symmetry = "rotational90"
+	// SymmetryHorizontal mirrors across the horizontal axis, so the top
+	// half matches the bottom half
+	SymmetryHorizontal Symmetry = "horizontal"
+	// SymmetryVertical mirrors across the vertical axis, so the left half
+	// matches the right half
+	SymmetryVertical Symmetry = "vertical"
+	// SymmetryDiagonal mirrors across the main diagonal
+	SymmetryDiagonal Symmetry = "diagonal"
+)
+
+// Symmetries lists every supported symmetry
+var Symmetries = []Symmetry{
+	SymmetryNone,
+	SymmetryRotational180,
+	SymmetryRotational90,
+	SymmetryHorizontal,
+	SymmetryVertical,
+	SymmetryDiagonal,
+}
+
+// ParseSymmetry looks a symmetry up by name
+func ParseSymmetry(name string) (Symmetry, error) {
+	for _, s := range Symmetries {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	names := make([]string, len(Symmetries))
+	for i, s := range Symmetries {
+		names[i] = string(s)
+	}
+	return "", fmt.Errorf("unknown symmetry %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// SetSymmetry makes clue removal keep the givens in the given pattern
+func (g *ClassicGenerator) SetSymmetry(s Symmetry) error {
+	if _, err := ParseSymmetry(string(s)); err != nil {
+		return err
+	}
+	g.symmetry = s
+	return nil
+}
+
+// orbits splits the cells of the grid into the sets the symmetry maps onto
+// each other, in cell order
+func (g *ClassicGenerator) orbits() [][]int {
+	size := g.size
+	images := func(r, c int) [][2]int {
+		switch g.symmetry {
+		case SymmetryRotational180:
+			return [][2]int{{size - 1 - r, size - 1 - c}}
+		case SymmetryRotational90:
+			return [][2]int{{c, size - 1 - r}, {size - 1 - r, size - 1 - c}, {size - 1 - c, r}}
+		case SymmetryHorizontal:
+			return [][2]int{{size - 1 - r, c}}
+		case SymmetryVertical:
+			return [][2]int{{r, size - 1 - c}}
+		case SymmetryDiagonal:
+			return [][2]int{{c, r}}
+		}
+		return nil
+	}
+
+	assigned := make([]bool, size*size)
+	var orbits [][]int
+	for cell := range assigned {
+		if assigned[cell] {
+			continue
+		}
+		orbit := []int{cell}
+		assigned[cell] = true
+		for _, img := range images(cell/size, cell%size) {
+			other := img[0]*size + img[1]
+			if !assigned[other] {
+				assigned[other] = true
+				orbit = append(orbit, other)
+			}
+		}
+		orbits = append(orbits, orbit)
+	}
+	return orbits
+}
@@ -0,0 +1,79 @@
+package generator_test
+
+import (
+	"sudoku_gen_go/internal/generator"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// mappings are the cell maps each symmetry keeps the givens under
+var mappings = map[generator.Symmetry][]func(size, r, c int) (int, int){
+	generator.SymmetryNone: nil,
+	generator.SymmetryRotational180: {
+		func(n, r, c int) (int, int) { return n - 1 - r, n - 1 - c },
+	},
+	generator.SymmetryRotational90: {
+		func(n, r, c int) (int, int) { return c, n - 1 - r },
+	},
+	generator.SymmetryHorizontal: {
+		func(n, r, c int) (int, int) { return n - 1 - r, c },
+	},
+	generator.SymmetryVertical: {
+		func(n, r, c int) (int, int) { return r, n - 1 - c },
+	},
+	generator.SymmetryDiagonal: {
+		func(n, r, c int) (int, int) { return c, r },
+	},
+}
+
+func TestSymmetryKeepsGivens(t *testing.T) {
+	for _, symmetry := range generator.Symmetries {
+		maps, ok := mappings[symmetry]
+		if !ok {
+			t.Fatalf("no mapping to check %s against", symmetry)
+		}
+		// Odd and even sizes, since a quarter turn of an odd grid keeps
+		// its centre cell in place
+		for _, size := range []int{4, 6, 9} {
+			gen := generator.NewClassicGenerator(size, types.Normal)
+			gen.SetSeed(1)
+			gen.SetThreads(1)
+			if err := gen.SetSymmetry(symmetry); err != nil {
+				t.Fatal(err)
+			}
+			if err := gen.SetDifficulty(4); err != nil {
+				t.Fatal(err)
+			}
+			grid := generate(t, gen)
+
+			for r := 0; r < size; r++ {
+				for c := 0; c < size; c++ {
+					for _, m := range maps {
+						mr, mc := m(size, r, c)
+						if (grid.Puzzle[r][c] == 0) != (grid.Puzzle[mr][mc] == 0) {
+							t.Fatalf("%s, %dx%d: cell %d,%d and its image %d,%d differ:\n%v",
+								symmetry, size, size, r, c, mr, mc, grid.Puzzle)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseSymmetry(t *testing.T) {
+	for _, symmetry := range generator.Symmetries {
+		if got, err := generator.ParseSymmetry(string(symmetry)); err != nil || got != symmetry {
+			t.Errorf("ParseSymmetry(%q) = %q, %v", symmetry, got, err)
+		}
+	}
+	for _, name := range []string{"", "rotational", "Diagonal", "spiral"} {
+		if _, err := generator.ParseSymmetry(name); err == nil {
+			t.Errorf("ParseSymmetry(%q) accepted an unknown symmetry", name)
+		}
+	}
+	gen := generator.NewClassicGenerator(9, types.Normal)
+	if err := gen.SetSymmetry("spiral"); err == nil {
+		t.Error("SetSymmetry accepted an unknown symmetry")
+	}
+}
@@ -0,0 +1,767 @@
+package grader
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+)
+
+// Technique is a human solving technique, ordered from easiest to hardest
+type Technique int
+
+const (
+	HiddenSingle Technique = iota
+	NakedSingle
+	// VariantRule is a digit ruled out by a variant's own rules, such as a
+	// cage sum
+	VariantRule
+	LockedCandidates
+	NakedPair
+	HiddenPair
+	NakedTriple
+	HiddenTriple
+	XWing
+	Swordfish
+	XYWing
+	SimpleChain
+	// Guessing means logic alone got stuck and a value had to be tried
+	Guessing
+)
+
+var techniqueNames = map[Technique]string{
+	HiddenSingle:     "hidden single",
+	NakedSingle:      "naked single",
+	VariantRule:      "variant rule",
+	LockedCandidates: "locked candidates",
+	NakedPair:        "naked pair",
+	HiddenPair:       "hidden pair",
+	NakedTriple:      "naked triple",
+	HiddenTriple:     "hidden triple",
+	XWing:            "x-wing",
+	Swordfish:        "swordfish",
+	XYWing:           "xy-wing",
+	SimpleChain:      "simple chain",
+	Guessing:         "guessing",
+}
+
+// weights is the score added every time a technique makes progress
+var weights = map[Technique]int{
+	HiddenSingle:     1,
+	NakedSingle:      2,
+	VariantRule:      3,
+	LockedCandidates: 5,
+	NakedPair:        10,
+	HiddenPair:       15,
+	NakedTriple:      20,
+	HiddenTriple:     25,
+	XWing:            40,
+	Swordfish:        60,
+	XYWing:           70,
+	SimpleChain:      90,
+	Guessing:         200,
+}
+
+func (t Technique) String() string {
+	if name, ok := techniqueNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("technique(%d)", int(t))
+}
+
+// MarshalText encodes the technique by name
+func (t Technique) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a technique name
+func (t *Technique) UnmarshalText(text []byte) error {
+	parsed, err := ParseTechnique(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Level maps the technique onto the generator's difficulty levels 1 to 5:
+// singles and variant rules, locked candidates, subsets, fish and wings,
+// then chains and guessing
+func (t Technique) Level() int {
+	switch {
+	case t <= VariantRule:
+		return 1
+	case t == LockedCandidates:
+		return 2
+	case t <= HiddenTriple:
+		return 3
+	case t <= XYWing:
+		return 4
+	}
+	return 5
+}
+
+// Techniques returns every technique in order of difficulty
+func Techniques() []Technique {
+	list := make([]Technique, 0, len(techniqueNames))
+	for t := HiddenSingle; t <= Guessing; t++ {
+		list = append(list, t)
+	}
+	return list
+}
+
+// ParseTechnique looks up a technique by its name
+func ParseTechnique(name string) (Technique, error) {
+	for t, n := range techniqueNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown technique %q", name)
+}
+
+// Band is an inclusive range of techniques a puzzle's hardest step must
+// fall into, e.g. "needs at least hidden pairs but no chains"
+type Band struct {
+	Min Technique `json:"min"`
+	Max Technique `json:"max"`
+}
+
+// Contains reports whether t lies within the band
+func (b Band) Contains(t Technique) bool {
+	return t >= b.Min && t <= b.Max
+}
+
+func (b Band) String() string {
+	return fmt.Sprintf("%v..%v", b.Min, b.Max)
+}
+
+// ParseBand reads a band written as "min..max", the form String produces
+func ParseBand(s string) (Band, error) {
+	lo, hi, ok := strings.Cut(s, "..")
+	if !ok {
+		return Band{}, fmt.Errorf("invalid band %q: want min..max", s)
+	}
+	from, err := ParseTechnique(strings.TrimSpace(lo))
+	if err != nil {
+		return Band{}, err
+	}
+	to, err := ParseTechnique(strings.TrimSpace(hi))
+	if err != nil {
+		return Band{}, err
+	}
+	if from > to {
+		return Band{}, fmt.Errorf("invalid band %q: %v is harder than %v", s, from, to)
+	}
+	return Band{Min: from, Max: to}, nil
+}
+
+// The grader reports unsolvable and ambiguous puzzles with the solver's
+// errors, so callers check one sentinel whichever package they used
+var (
+	// ErrNoSolution is returned when the puzzle cannot be solved at all
+	ErrNoSolution = solver.ErrNoSolution
+	// ErrMultipleSolutions is returned when the puzzle has more than one solution
+	ErrMultipleSolutions = solver.ErrNotUnique
+)
+
+// Result describes how hard a puzzle is for a human solver
+type Result struct {
+	// Hardest is the most difficult technique the puzzle required
+	Hardest Technique `json:"hardest"`
+	// Score is the sum of technique weights over every solving step
+	Score int `json:"score"`
+	// Steps counts how often each technique was applied
+	Steps map[Technique]int `json:"steps"`
+}
+
+// Grade solves the puzzle step by step with human techniques, always using
+// the easiest one that makes progress, and reports what it needed. Regions
+// are taken from grid.SubGrids, so jigsaw layouts are graded correctly.
+// All-different rules of a variant that cover every digit count as houses
+// for every technique; other rules eliminate through VariantRule.
+func Grade(grid *types.Grid) (*Result, error) {
+	switch solver.CountSolutions(grid, 2) {
+	case 0:
+		return nil, ErrNoSolution
+	case 1:
+	default:
+		return nil, ErrMultipleSolutions
+	}
+	solution, err := solver.Solve(grid)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBoard(grid)
+	result := &Result{Steps: make(map[Technique]int)}
+
+	for !b.solved() {
+		technique, ok := b.step()
+		if !ok {
+			b.guess(solution)
+			technique = Guessing
+		}
+
+		result.Steps[technique]++
+		result.Score += weights[technique]
+		if technique > result.Hardest {
+			result.Hardest = technique
+		}
+	}
+
+	return result, nil
+}
+
+// board is the candidate grid worked on by the grader
+type board struct {
+	size       int
+	values     []int
+	cands      []uint64
+	houses     [][]int
+	rows       [][]int
+	cols       [][]int
+	cellHouses [][]int
+	peers      [][]int
+	sees       [][]bool
+	rules      []types.Constraint
+}
+
+func newBoard(grid *types.Grid) *board {
+	size := grid.Size
+	n := size * size
+	b := &board{
+		size:       size,
+		values:     make([]int, n),
+		cands:      make([]uint64, n),
+		cellHouses: make([][]int, n),
+		peers:      make([][]int, n),
+		sees:       make([][]bool, n),
+	}
+
+	for i := 0; i < size; i++ {
+		row := make([]int, size)
+		col := make([]int, size)
+		for j := 0; j < size; j++ {
+			row[j] = i*size + j
+			col[j] = j*size + i
+		}
+		b.rows = append(b.rows, row)
+		b.cols = append(b.cols, col)
+	}
+	regions := grid.SubGrids
+	if len(regions) == 0 {
+		regions = types.BoxRegions(size, grid.BoxWidth, grid.BoxHeight)
+	}
+	b.houses = append(b.houses, b.rows...)
+	b.houses = append(b.houses, b.cols...)
+	b.houses = append(b.houses, regions...)
+
+	// Smaller all-different groups only make their cells see each other,
+	// since a digit missing from one does not have to go anywhere
+	var groups [][]int
+	for _, rule := range grid.Constraints() {
+		group, ok := rule.(types.AllDifferent)
+		switch {
+		case !ok:
+			b.rules = append(b.rules, rule)
+		case len(group) == size:
+			b.houses = append(b.houses, group)
+		default:
+			groups = append(groups, group)
+		}
+	}
+
+	for h, house := range b.houses {
+		for _, cell := range house {
+			b.cellHouses[cell] = append(b.cellHouses[cell], h)
+		}
+	}
+	for cell := 0; cell < n; cell++ {
+		b.sees[cell] = make([]bool, n)
+	}
+	for _, group := range append(groups, b.houses...) {
+		for _, cell := range group {
+			for _, other := range group {
+				if other != cell && !b.sees[cell][other] {
+					b.sees[cell][other] = true
+					b.peers[cell] = append(b.peers[cell], other)
+				}
+			}
+		}
+	}
+
+	all := uint64(1)<<(size+1) - 2
+	for i := range b.cands {
+		b.cands[i] = all
+	}
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			if num := grid.Puzzle[row][col]; num != 0 {
+				b.place(row*size+col, num)
+			}
+		}
+	}
+
+	return b
+}
+
+func (b *board) solved() bool {
+	for _, v := range b.values {
+		if v == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *board) place(cell, num int) {
+	b.values[cell] = num
+	b.cands[cell] = 1 << num
+	for _, peer := range b.peers[cell] {
+		b.cands[peer] &^= 1 << num
+	}
+}
+
+// eliminate removes the digits in mask from cell and reports whether
+// anything changed
+func (b *board) eliminate(cell int, mask uint64) bool {
+	if b.values[cell] != 0 || b.cands[cell]&mask == 0 {
+		return false
+	}
+	b.cands[cell] &^= mask
+	return true
+}
+
+// step applies the easiest technique that makes progress
+func (b *board) step() (Technique, bool) {
+	techniques := []struct {
+		technique Technique
+		apply     func() bool
+	}{
+		{HiddenSingle, b.hiddenSingle},
+		{NakedSingle, b.nakedSingle},
+		{VariantRule, b.variantRule},
+		{LockedCandidates, b.lockedCandidates},
+		{NakedPair, func() bool { return b.nakedSubset(2) }},
+		{HiddenPair, func() bool { return b.hiddenSubset(2) }},
+		{NakedTriple, func() bool { return b.nakedSubset(3) }},
+		{HiddenTriple, func() bool { return b.hiddenSubset(3) }},
+		{XWing, func() bool { return b.fish(2) }},
+		{Swordfish, func() bool { return b.fish(3) }},
+		{XYWing, b.xyWing},
+		{SimpleChain, b.simpleChain},
+	}
+
+	for _, t := range techniques {
+		if t.apply() {
+			return t.technique, true
+		}
+	}
+	return 0, false
+}
+
+// guess fills the empty cell with the fewest candidates from the solution
+func (b *board) guess(solution [][]int) {
+	best, bestCount := -1, b.size+1
+	for cell, v := range b.values {
+		if v == 0 {
+			if count := bits.OnesCount64(b.cands[cell]); count < bestCount {
+				best, bestCount = cell, count
+			}
+		}
+	}
+	b.place(best, solution[best/b.size][best%b.size])
+}
+
+func (b *board) nakedSingle() bool {
+	for cell, v := range b.values {
+		if v == 0 && bits.OnesCount64(b.cands[cell]) == 1 {
+			b.place(cell, bits.TrailingZeros64(b.cands[cell]))
+			return true
+		}
+	}
+	return false
+}
+
+// variantRule lets each rule of the variant remove the digits it rules out
+func (b *board) variantRule() bool {
+	for _, rule := range b.rules {
+		cells := rule.Cells()
+		cands := make([]uint64, len(cells))
+		for i, cell := range cells {
+			cands[i] = b.cands[cell]
+		}
+		// The puzzle has a solution, so the rule can always be kept
+		rule.Restrict(cands)
+		changed := false
+		for i, cell := range cells {
+			if b.eliminate(cell, b.cands[cell]&^cands[i]) {
+				changed = true
+			}
+		}
+		if changed {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *board) hiddenSingle() bool {
+	for _, house := range b.houses {
+		for num := 1; num <= b.size; num++ {
+			cells := b.positions(house, num)
+			if len(cells) == 1 {
+				b.place(cells[0], num)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// positions returns the empty cells of house that can still hold num
+func (b *board) positions(house []int, num int) []int {
+	var cells []int
+	for _, cell := range house {
+		if b.values[cell] == num {
+			return nil
+		}
+		if b.values[cell] == 0 && b.cands[cell]&(1<<num) != 0 {
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+// lockedCandidates handles pointing and claiming: if every position of a
+// digit in one house also lies in a second house, the digit is removed from
+// the rest of the second house
+func (b *board) lockedCandidates() bool {
+	for h, house := range b.houses {
+		for num := 1; num <= b.size; num++ {
+			cells := b.positions(house, num)
+			if len(cells) < 2 {
+				continue
+			}
+			for _, other := range b.cellHouses[cells[0]] {
+				if other == h || !b.containsAll(other, cells) {
+					continue
+				}
+				changed := false
+				for _, cell := range b.houses[other] {
+					if !b.containsCell(house, cell) && b.eliminate(cell, 1<<num) {
+						changed = true
+					}
+				}
+				if changed {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (b *board) containsAll(house int, cells []int) bool {
+	for _, cell := range cells {
+		if !b.containsCell(b.houses[house], cell) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *board) containsCell(house []int, cell int) bool {
+	for _, c := range house {
+		if c == cell {
+			return true
+		}
+	}
+	return false
+}
+
+// nakedSubset finds k cells in a house whose candidates together hold
+// exactly k digits and removes those digits from the rest of the house
+func (b *board) nakedSubset(k int) bool {
+	for _, house := range b.houses {
+		var cells []int
+		for _, cell := range house {
+			if count := bits.OnesCount64(b.cands[cell]); b.values[cell] == 0 && count >= 2 && count <= k {
+				cells = append(cells, cell)
+			}
+		}
+
+		found := false
+		combinations(len(cells), k, func(picked []int) bool {
+			var mask uint64
+			for _, i := range picked {
+				mask |= b.cands[cells[i]]
+			}
+			if bits.OnesCount64(mask) != k {
+				return false
+			}
+			for _, cell := range house {
+				if !containsIndex(cells, picked, cell) && b.eliminate(cell, mask) {
+					found = true
+				}
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenSubset finds k digits confined to the same k cells of a house and
+// removes every other candidate from those cells
+func (b *board) hiddenSubset(k int) bool {
+	for _, house := range b.houses {
+		var nums []int
+		var places [][]int
+		for num := 1; num <= b.size; num++ {
+			if cells := b.positions(house, num); len(cells) >= 2 && len(cells) <= k {
+				nums = append(nums, num)
+				places = append(places, cells)
+			}
+		}
+
+		found := false
+		combinations(len(nums), k, func(picked []int) bool {
+			var mask uint64
+			union := make(map[int]bool)
+			for _, i := range picked {
+				mask |= 1 << nums[i]
+				for _, cell := range places[i] {
+					union[cell] = true
+				}
+			}
+			if len(union) != k {
+				return false
+			}
+			for cell := range union {
+				if b.eliminate(cell, ^mask) {
+					found = true
+				}
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// fish implements X-Wing (k=2) and Swordfish (k=3) on rows and columns
+func (b *board) fish(k int) bool {
+	row := func(cell int) int { return cell / b.size }
+	col := func(cell int) int { return cell % b.size }
+
+	return b.fishOn(k, b.rows, b.cols, row, col) || b.fishOn(k, b.cols, b.rows, col, row)
+}
+
+// fishOn looks for k base lines whose positions of a digit fall into k
+// cover lines, and removes the digit from the rest of the cover lines
+func (b *board) fishOn(k int, base, cover [][]int, baseIndex, coverIndex func(int) int) bool {
+	for num := 1; num <= b.size; num++ {
+		var lines []int
+		var spots []uint64
+		for i, line := range base {
+			cells := b.positions(line, num)
+			if len(cells) < 2 || len(cells) > k {
+				continue
+			}
+			var mask uint64
+			for _, cell := range cells {
+				mask |= 1 << coverIndex(cell)
+			}
+			lines = append(lines, i)
+			spots = append(spots, mask)
+		}
+
+		found := false
+		combinations(len(lines), k, func(picked []int) bool {
+			var mask uint64
+			inBase := make(map[int]bool)
+			for _, i := range picked {
+				mask |= spots[i]
+				inBase[lines[i]] = true
+			}
+			if bits.OnesCount64(mask) != k {
+				return false
+			}
+			for c := range cover {
+				if mask&(1<<c) == 0 {
+					continue
+				}
+				for _, cell := range cover[c] {
+					if !inBase[baseIndex(cell)] && b.eliminate(cell, 1<<num) {
+						found = true
+					}
+				}
+			}
+			return found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// xyWing looks for a bivalue pivot XY seeing pincers XZ and YZ, which
+// removes Z from every cell that sees both pincers
+func (b *board) xyWing() bool {
+	for pivot, v := range b.values {
+		if v != 0 || bits.OnesCount64(b.cands[pivot]) != 2 {
+			continue
+		}
+		var pincers []int
+		for _, peer := range b.peers[pivot] {
+			if b.values[peer] == 0 && bits.OnesCount64(b.cands[peer]) == 2 &&
+				bits.OnesCount64(b.cands[peer]&b.cands[pivot]) == 1 {
+				pincers = append(pincers, peer)
+			}
+		}
+
+		for i, a := range pincers {
+			for _, c := range pincers[i+1:] {
+				shared := b.cands[a] & b.cands[c] &^ b.cands[pivot]
+				if bits.OnesCount64(shared) != 1 || b.cands[a] == b.cands[c] {
+					continue
+				}
+				changed := false
+				for _, cell := range b.peers[a] {
+					if cell != c && b.sees[c][cell] && b.eliminate(cell, shared) {
+						changed = true
+					}
+				}
+				if changed {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// simpleChain colors the chains of conjugate pairs of a single digit. Two
+// cells of one color in the same house prove that color false, and a cell
+// seeing both colors cannot hold the digit.
+func (b *board) simpleChain() bool {
+	for num := 1; num <= b.size; num++ {
+		links := make(map[int][]int)
+		for _, house := range b.houses {
+			if cells := b.positions(house, num); len(cells) == 2 {
+				links[cells[0]] = append(links[cells[0]], cells[1])
+				links[cells[1]] = append(links[cells[1]], cells[0])
+			}
+		}
+
+		// Chains start from cells in order, not map order, so grading the
+		// same puzzle always takes the same steps
+		color := make(map[int]int)
+		for start := range b.values {
+			if _, seen := color[start]; seen || links[start] == nil {
+				continue
+			}
+			component := b.colorChain(start, links, color)
+			if b.applyColoring(num, component, color) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *board) colorChain(start int, links map[int][]int, color map[int]int) []int {
+	color[start] = 0
+	component := []int{start}
+	for i := 0; i < len(component); i++ {
+		cell := component[i]
+		for _, next := range links[cell] {
+			if _, seen := color[next]; !seen {
+				color[next] = 1 - color[cell]
+				component = append(component, next)
+			}
+		}
+	}
+	return component
+}
+
+func (b *board) applyColoring(num int, component []int, color map[int]int) bool {
+	if len(component) < 3 {
+		return false
+	}
+
+	// Color wrap: a color appearing twice in one house is false
+	for i, a := range component {
+		for _, c := range component[i+1:] {
+			if color[a] == color[c] && b.sees[a][c] {
+				changed := false
+				for _, cell := range component {
+					if color[cell] == color[a] && b.eliminate(cell, 1<<num) {
+						changed = true
+					}
+				}
+				return changed
+			}
+		}
+	}
+
+	// Color trap: a cell seeing both colors loses the digit
+	inChain := make(map[int]bool, len(component))
+	for _, cell := range component {
+		inChain[cell] = true
+	}
+	changed := false
+	for cell := range b.values {
+		if inChain[cell] || b.values[cell] != 0 || b.cands[cell]&(1<<num) == 0 {
+			continue
+		}
+		var seen [2]bool
+		for _, c := range component {
+			if b.sees[cell][c] {
+				seen[color[c]] = true
+			}
+		}
+		if seen[0] && seen[1] && b.eliminate(cell, 1<<num) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// combinations calls visit with every k-subset of 0..n-1 until it returns true
+func combinations(n, k int, visit func([]int) bool) {
+	picked := make([]int, 0, k)
+	var rec func(start int) bool
+	rec = func(start int) bool {
+		if len(picked) == k {
+			return visit(picked)
+		}
+		for i := start; i <= n-(k-len(picked)); i++ {
+			picked = append(picked, i)
+			if rec(i + 1) {
+				return true
+			}
+			picked = picked[:len(picked)-1]
+		}
+		return false
+	}
+	rec(0)
+}
+
+// containsIndex reports whether cell is one of the picked entries of cells
+func containsIndex(cells, picked []int, cell int) bool {
+	for _, i := range picked {
+		if cells[i] == cell {
+			return true
+		}
+	}
+	return false
+}
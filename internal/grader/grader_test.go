@@ -0,0 +1,127 @@
+package grader_test
+
+import (
+	"errors"
+	"reflect"
+	"sudoku_gen_go/internal/grader"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// gradeCases are puzzles that need singles and exactly one other
+// technique, written row by row with 0 for empty cells
+var gradeCases = []struct {
+	name   string
+	puzzle string
+	cages  []types.Cage
+	steps  map[grader.Technique]int
+}{
+	{"hidden single",
+		"500800000010002700000010090070001840080007006600000000005038060400000037000500010", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 57}},
+	{"naked single",
+		"278000000610007000000000000006800100350040009000003200005091007107000040063005090", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 53, grader.NakedSingle: 2}},
+	{"variant rule", "0300001000000000", []types.Cage{
+		{Cells: []int{12, 13}, Sum: 6}, {Cells: []int{8, 4, 9}, Sum: 8},
+		{Cells: []int{0, 1, 5}, Sum: 6}, {Cells: []int{15, 14, 11}, Sum: 6},
+		{Cells: []int{10, 6, 7}, Sum: 8}, {Cells: []int{2, 3}, Sum: 6},
+	}, map[grader.Technique]int{grader.HiddenSingle: 14, grader.VariantRule: 3}},
+	{"locked candidates",
+		"000000004030807000000000290107000000400070600053200009009080030070300000000610740", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 57, grader.LockedCandidates: 1}},
+	{"naked pair",
+		"070000001006080700009007645000001006000005000400030580890070000005000000103050907", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 55, grader.NakedPair: 1}},
+	{"hidden pair",
+		"400000805756000400000006000009000000000305204800102006000420001000980002007001900", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 55, grader.HiddenPair: 1}},
+	{"naked triple",
+		"610000003500007000004000007290603000000004030060590070050700020000005608800209000", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 55, grader.NakedTriple: 1}},
+	{"hidden triple",
+		"008000000060000097035700200000409100004007005000500008007001030006000000310890000", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 57, grader.HiddenTriple: 1}},
+	{"x-wing",
+		"080000000000000394023700000001000040092050000000002706000020900000006007160090003", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 58, grader.XWing: 1}},
+	{"swordfish",
+		"000004700004670090300000005530000010040120000009000300910300800407980001000050000", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 55, grader.Swordfish: 1}},
+	{"xy-wing",
+		"007000900000005000314000007000021435000700010060000000150040090000300002800190060", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 56, grader.XYWing: 1}},
+	{"simple chain",
+		"400750000000000001603000900000002090009300684000010050030001006800900500002000010", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 57, grader.SimpleChain: 1}},
+	{"guessing",
+		"010080005000005000800706200009030001600000008000004003000050840100090000907040000", nil,
+		map[grader.Technique]int{grader.HiddenSingle: 57, grader.Guessing: 1}},
+}
+
+// parseGrid reads a puzzle written row by row, adding the killer variant
+// when it has cages
+func parseGrid(t *testing.T, puzzle string, cages []types.Cage) *types.Grid {
+	t.Helper()
+	size := 1
+	for size*size < len(puzzle) {
+		size++
+	}
+	grid := types.NewGrid(size, types.Normal)
+	grid.SubGrids = types.BoxRegions(size, grid.BoxWidth, grid.BoxHeight)
+	for i, ch := range puzzle {
+		if ch < '0' || ch > '9' {
+			t.Fatalf("invalid digit %q in puzzle", ch)
+		}
+		grid.Puzzle[i/size][i%size] = int(ch - '0')
+	}
+	if len(cages) > 0 {
+		grid.Variants = []types.SudokuType{types.Killer}
+		grid.Cages = cages
+	}
+	return grid
+}
+
+func TestGradeTechniques(t *testing.T) {
+	for _, tc := range gradeCases {
+		result, err := grader.Grade(parseGrid(t, tc.puzzle, tc.cages))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if want, err := grader.ParseTechnique(tc.name); err != nil || result.Hardest != want {
+			t.Errorf("%s: hardest technique is %v", tc.name, result.Hardest)
+		}
+		if !reflect.DeepEqual(result.Steps, tc.steps) {
+			t.Errorf("%s: steps %v, want %v", tc.name, result.Steps, tc.steps)
+		}
+	}
+}
+
+func TestGradeCoversEveryTechnique(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tc := range gradeCases {
+		covered[tc.name] = true
+	}
+	for _, technique := range grader.Techniques() {
+		if !covered[technique.String()] {
+			t.Errorf("no puzzle needs %v", technique)
+		}
+	}
+}
+
+func TestGradeRejectsBadPuzzles(t *testing.T) {
+	// The puzzles are minimal, so any given taken away leaves more
+	// solutions
+	open := parseGrid(t, gradeCases[0].puzzle, nil)
+	open.Puzzle[0][0] = 0
+	if _, err := grader.Grade(open); !errors.Is(err, grader.ErrMultipleSolutions) {
+		t.Errorf("ambiguous puzzle: got %v, want ErrMultipleSolutions", err)
+	}
+
+	// A second 8 in the first row
+	broken := parseGrid(t, gradeCases[0].puzzle, nil)
+	broken.Puzzle[0][1] = 8
+	if _, err := grader.Grade(broken); !errors.Is(err, grader.ErrNoSolution) {
+		t.Errorf("unsolvable puzzle: got %v, want ErrNoSolution", err)
+	}
+}
@@ -0,0 +1,298 @@
+package solver
+
+import (
+	"fmt"
+	"math/bits"
+	"sudoku_gen_go/internal/types"
+)
+
+// DLX solves the puzzle as an exact cover problem with Knuth's Dancing
+// Links. Every empty cell and every digit missing from a house is a column
+// that must be covered exactly once, and every candidate placement is a
+// row covering its cell and the digit in each of its houses. All-different
+// rules with fewer cells than digits become secondary columns, covered at
+// most once; other variant rules are not exact cover problems and are
+// rejected with ErrUnsupported.
+type DLX struct{}
+
+// Solve implements Solver
+func (DLX) Solve(grid *types.Grid, opts Options) ([][]int, error) {
+	d, err := newDancer(grid, opts)
+	if err != nil {
+		return nil, unsolvable(err)
+	}
+
+	count := d.run(1)
+	if d.err != nil {
+		return nil, d.err
+	}
+	if count == 0 {
+		return nil, ErrNoSolution
+	}
+	return toRows(d.size, d.solution), nil
+}
+
+// CountSolutions implements Solver
+func (DLX) CountSolutions(grid *types.Grid, limit int, opts Options) (int, error) {
+	d, err := newDancer(grid, opts)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	count := d.run(limit)
+	if d.err != nil {
+		return 0, d.err
+	}
+	return count, nil
+}
+
+// dancer is the toroidal linked matrix of one DLX run. Node 0 is the root,
+// nodes 1..columns are the column headers and the rest are row nodes.
+type dancer struct {
+	size        int
+	left, right []int
+	up, down    []int
+	column      []int
+	placement   []int
+	count       []int
+	placements  [][2]int
+	cells       []int
+	solution    []int
+	opts        Options
+	nodes       int
+	err         error
+}
+
+func newDancer(grid *types.Grid, opts Options) (*dancer, error) {
+	// The backtracking setup validates the grid and tracks the givens
+	s, err := newSearch(grid, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.dancer()
+}
+
+// dancer builds the exact cover matrix of the search's houses and givens
+func (s *search) dancer() (*dancer, error) {
+	if len(s.rules) > 0 {
+		return nil, fmt.Errorf("%w: dlx only handles all-different rules", ErrUnsupported)
+	}
+
+	d := &dancer{
+		size:  s.size,
+		cells: append([]int(nil), s.cells...),
+		opts:  s.opts,
+	}
+
+	// Columns: one per empty cell, one per missing digit of each house,
+	// complete houses first so the secondary columns come last
+	cellColumn := make([]int, len(s.cells))
+	houseColumn := make([][]int, len(s.houses))
+	columns := 0
+	for idx, num := range s.cells {
+		if num == 0 {
+			columns++
+			cellColumn[idx] = columns
+		}
+	}
+	primary := 0
+	for _, complete := range []bool{true, false} {
+		for h, house := range s.houses {
+			if (len(house) == s.size) != complete {
+				continue
+			}
+			houseColumn[h] = make([]int, s.size+1)
+			for num := 1; num <= s.size; num++ {
+				if s.used[h]&(1<<num) == 0 {
+					columns++
+					houseColumn[h][num] = columns
+				}
+			}
+		}
+		if complete {
+			primary = columns
+		}
+	}
+
+	nodes := columns + 1
+	for idx, num := range s.cells {
+		if num == 0 {
+			houses := 1 + len(s.cellHouses[idx]) + len(s.extra[idx])
+			nodes += bits.OnesCount64(s.candidates(idx)) * houses
+		}
+	}
+	d.grow(nodes)
+
+	d.addHeaders(primary, columns)
+	for idx, num := range s.cells {
+		if num != 0 {
+			continue
+		}
+		for cands := s.candidates(idx); cands != 0; cands &= cands - 1 {
+			digit := bits.TrailingZeros64(cands)
+			h := s.cellHouses[idx]
+			cols := []int{
+				cellColumn[idx],
+				houseColumn[h[0]][digit],
+				houseColumn[h[1]][digit],
+				houseColumn[h[2]][digit],
+			}
+			for _, e := range s.extra[idx] {
+				cols = append(cols, houseColumn[e][digit])
+			}
+			d.addRow(idx, digit, cols)
+		}
+	}
+
+	return d, nil
+}
+
+// grow reserves room for the given number of nodes
+func (d *dancer) grow(nodes int) {
+	d.left = make([]int, 0, nodes)
+	d.right = make([]int, 0, nodes)
+	d.up = make([]int, 0, nodes)
+	d.down = make([]int, 0, nodes)
+	d.column = make([]int, 0, nodes)
+	d.placement = make([]int, 0, nodes)
+}
+
+// addHeaders adds the root and the column headers. Only the primary
+// columns 1..primary are linked to the root; the rest link to themselves,
+// so a solution need not cover them.
+func (d *dancer) addHeaders(primary, columns int) {
+	for i := 0; i <= columns; i++ {
+		d.left = append(d.left, i-1)
+		d.right = append(d.right, i+1)
+		d.up = append(d.up, i)
+		d.down = append(d.down, i)
+		d.column = append(d.column, i)
+		d.placement = append(d.placement, -1)
+		d.count = append(d.count, 0)
+		if i > primary {
+			d.left[i], d.right[i] = i, i
+		}
+	}
+	d.left[0] = primary
+	d.right[primary] = 0
+}
+
+func (d *dancer) addRow(idx, digit int, cols []int) {
+	p := len(d.placements)
+	d.placements = append(d.placements, [2]int{idx, digit})
+
+	first := len(d.left)
+	for i, col := range cols {
+		node := len(d.left)
+		d.left = append(d.left, node-1)
+		d.right = append(d.right, first)
+		if i == 0 {
+			d.left[node] = first + len(cols) - 1
+		} else {
+			d.right[node-1] = node
+		}
+		d.up = append(d.up, d.up[col])
+		d.down = append(d.down, col)
+		d.down[d.up[col]] = node
+		d.up[col] = node
+		d.column = append(d.column, col)
+		d.placement = append(d.placement, p)
+		d.count[col]++
+	}
+}
+
+func (d *dancer) cover(col int) {
+	d.right[d.left[col]] = d.right[col]
+	d.left[d.right[col]] = d.left[col]
+	for row := d.down[col]; row != col; row = d.down[row] {
+		for node := d.right[row]; node != row; node = d.right[node] {
+			d.down[d.up[node]] = d.down[node]
+			d.up[d.down[node]] = d.up[node]
+			d.count[d.column[node]]--
+		}
+	}
+}
+
+func (d *dancer) uncover(col int) {
+	for row := d.up[col]; row != col; row = d.up[row] {
+		for node := d.left[row]; node != row; node = d.left[node] {
+			d.count[d.column[node]]++
+			d.down[d.up[node]] = node
+			d.up[d.down[node]] = node
+		}
+	}
+	d.right[d.left[col]] = col
+	d.left[d.right[col]] = col
+}
+
+// run counts exact covers up to limit, remembering the first one found
+func (d *dancer) run(limit int) int {
+	if d.aborted() {
+		return 0
+	}
+
+	if d.right[0] == 0 {
+		if d.solution == nil {
+			d.solution = append([]int(nil), d.cells...)
+		}
+		return 1
+	}
+
+	// Branch on the column with the fewest rows left
+	col := d.right[0]
+	for c := d.right[col]; c != 0; c = d.right[c] {
+		if d.count[c] < d.count[col] {
+			col = c
+		}
+	}
+	if d.count[col] == 0 {
+		return 0
+	}
+
+	var rows []int
+	for row := d.down[col]; row != col; row = d.down[row] {
+		rows = append(rows, row)
+	}
+	if d.opts.Shuffle != nil {
+		d.opts.Shuffle(rows)
+	}
+
+	count := 0
+	d.cover(col)
+	for _, row := range rows {
+		for node := d.right[row]; node != row; node = d.right[node] {
+			d.cover(d.column[node])
+		}
+		placement := d.placements[d.placement[row]]
+		d.cells[placement[0]] = placement[1]
+
+		count += d.run(limit - count)
+
+		d.cells[placement[0]] = 0
+		for node := d.left[row]; node != row; node = d.left[node] {
+			d.uncover(d.column[node])
+		}
+		if count >= limit || d.err != nil {
+			break
+		}
+	}
+	d.uncover(col)
+
+	return count
+}
+
+func (d *dancer) aborted() bool {
+	if d.err != nil {
+		return true
+	}
+	d.nodes++
+	if d.opts.NodeLimit > 0 && d.nodes > d.opts.NodeLimit {
+		d.err = ErrNodeLimit
+	} else if d.opts.Stop != nil && d.opts.Stop() {
+		d.err = ErrStopped
+	}
+	return d.err != nil
+}
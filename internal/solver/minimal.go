@@ -0,0 +1,69 @@
+package solver
+
+import (
+	"errors"
+	"sudoku_gen_go/internal/types"
+)
+
+// ErrNotUnique is returned when a puzzle has more than one solution
+var ErrNotUnique = errors.New("puzzle has more than one solution")
+
+// RedundantClues returns the givens, as row*Size+col cell indices in
+// ascending order, that could each be removed on its own without losing
+// the unique solution. A puzzle is minimal when there are none. Removing
+// one redundant clue can make others necessary, so they cannot all be
+// removed at once. It fails with ErrNoSolution when the puzzle cannot be
+// solved, broken givens included, and ErrNotUnique when it has more than
+// one solution.
+func RedundantClues(grid *types.Grid) ([]int, error) {
+	return RedundantCluesWith(grid, Options{})
+}
+
+// RedundantCluesWith is RedundantClues with explicit options
+func RedundantCluesWith(grid *types.Grid, opts Options) ([]int, error) {
+	count, err := CountSolutionsWith(grid, 2, opts)
+	if err != nil {
+		return nil, unsolvable(err)
+	}
+	switch count {
+	case 0:
+		return nil, ErrNoSolution
+	case 1:
+	default:
+		return nil, ErrNotUnique
+	}
+
+	// Work on a copy so the caller's grid is never modified, even briefly
+	puzzle := make([][]int, len(grid.Puzzle))
+	for i, row := range grid.Puzzle {
+		puzzle[i] = append([]int(nil), row...)
+	}
+	probe := *grid
+	probe.Puzzle = puzzle
+
+	var redundant []int
+	for r, row := range puzzle {
+		for c, num := range row {
+			if num == 0 {
+				continue
+			}
+			row[c] = 0
+			count, err := CountSolutionsWith(&probe, 2, opts)
+			row[c] = num
+			if err != nil {
+				return nil, err
+			}
+			if count == 1 {
+				redundant = append(redundant, r*grid.Size+c)
+			}
+		}
+	}
+	return redundant, nil
+}
+
+// IsMinimal reports whether every given of a uniquely solvable puzzle is
+// needed for uniqueness
+func IsMinimal(grid *types.Grid) (bool, error) {
+	redundant, err := RedundantClues(grid)
+	return len(redundant) == 0 && err == nil, err
+}
@@ -0,0 +1,80 @@
+package solver_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// minimalPuzzle needs every one of its 24 givens
+const minimalPuzzle = "500800000010002700000010090070001840080007006600000000005038060400000037000500010"
+
+// parsePuzzle reads a 9x9 puzzle written row by row, 0 for empty cells
+func parsePuzzle(puzzle string) *types.Grid {
+	grid := types.NewGrid(9, types.Normal)
+	grid.SubGrids = types.BoxRegions(9, 3, 3)
+	for i, ch := range puzzle {
+		grid.Puzzle[i/9][i%9] = int(ch - '0')
+	}
+	return grid
+}
+
+func TestMinimalPuzzle(t *testing.T) {
+	grid := parsePuzzle(minimalPuzzle)
+	before := grid.Clone()
+	redundant, err := solver.RedundantClues(grid)
+	if err != nil || len(redundant) != 0 {
+		t.Errorf("redundant clues %v (%v), want none", redundant, err)
+	}
+	if minimal, err := solver.IsMinimal(grid); !minimal || err != nil {
+		t.Errorf("IsMinimal = %v, %v, want true", minimal, err)
+	}
+	if !reflect.DeepEqual(grid, before) {
+		t.Error("RedundantClues changed the grid")
+	}
+}
+
+func TestRedundantClue(t *testing.T) {
+	grid := parsePuzzle(minimalPuzzle)
+	solution, err := solver.Solve(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Any digit of the solution added to a minimal puzzle can go again
+	grid.Puzzle[0][1] = solution[0][1]
+
+	redundant, err := solver.RedundantClues(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(redundant, 1) {
+		t.Errorf("redundant clues %v miss the added cell 1", redundant)
+	}
+	if !slices.IsSorted(redundant) {
+		t.Errorf("redundant clues %v are not in cell order", redundant)
+	}
+	if minimal, err := solver.IsMinimal(grid); minimal || err != nil {
+		t.Errorf("IsMinimal = %v, %v, want false", minimal, err)
+	}
+}
+
+func TestRedundantCluesNeedUniquePuzzle(t *testing.T) {
+	grid := parsePuzzle(minimalPuzzle)
+	grid.Puzzle[0][0] = 0
+	if _, err := solver.RedundantClues(grid); !errors.Is(err, solver.ErrNotUnique) {
+		t.Errorf("got %v, want ErrNotUnique", err)
+	}
+	if minimal, err := solver.IsMinimal(grid); minimal || !errors.Is(err, solver.ErrNotUnique) {
+		t.Errorf("IsMinimal = %v, %v, want false with ErrNotUnique", minimal, err)
+	}
+
+	// A second 5 in the first row
+	grid = parsePuzzle(minimalPuzzle)
+	grid.Puzzle[0][1] = 5
+	if _, err := solver.RedundantClues(grid); !errors.Is(err, solver.ErrNoSolution) {
+		t.Errorf("got %v, want ErrNoSolution", err)
+	}
+}
@@ -0,0 +1,127 @@
+package solver
+
+import (
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// ruleSearch sets up a search of an empty 4x4 grid with the given rules,
+// which no variant produces on its own
+func ruleSearch(t *testing.T, rules []types.Constraint) *search {
+	t.Helper()
+	s, err := newSearch(types.NewGrid(4, types.Normal), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rule := range rules {
+		group, ok := rule.(types.AllDifferent)
+		if !ok {
+			s.rules = append(s.rules, rule)
+			s.restricted = append(s.restricted, nil)
+			continue
+		}
+		h := len(s.houses)
+		s.houses = append(s.houses, group)
+		s.used = append(s.used, 0)
+		for _, idx := range group {
+			s.extra[idx] = append(s.extra[idx], h)
+		}
+	}
+	return s
+}
+
+// bruteForce counts the filled 4x4 grids that keep the rules by trying
+// every one
+func bruteForce(rules []types.Constraint) int {
+	var cells [16]int
+	fits := func(idx, num int) bool {
+		row, col := idx/4, idx%4
+		for other := 0; other < idx; other++ {
+			r, c := other/4, other%4
+			if cells[other] == num && (r == row || c == col || (r/2 == row/2 && c/2 == col/2)) {
+				return false
+			}
+		}
+		return true
+	}
+	var fill func(idx int) int
+	fill = func(idx int) int {
+		if idx == len(cells) {
+			for _, rule := range rules {
+				var values []int
+				for _, cell := range rule.Cells() {
+					values = append(values, cells[cell])
+				}
+				if !types.Satisfies(rule, values) {
+					return 0
+				}
+			}
+			return 1
+		}
+		count := 0
+		for num := 1; num <= 4; num++ {
+			if fits(idx, num) {
+				cells[idx] = num
+				count += fill(idx + 1)
+			}
+		}
+		cells[idx] = 0
+		return count
+	}
+	return fill(0)
+}
+
+func TestRulesCountLikeBruteForce(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []types.Constraint
+	}{
+		{"none", nil},
+		{"partial all different", []types.Constraint{types.AllDifferent{0, 10}}},
+		{"two partial all different", []types.Constraint{types.AllDifferent{0, 7, 10}, types.AllDifferent{3, 13}}},
+		{"less", []types.Constraint{types.Relation{A: 0, B: 5, Kind: types.Less}}},
+		{"consecutive", []types.Constraint{types.Relation{A: 3, B: 6, Kind: types.Consecutive}}},
+		{"order line", []types.Constraint{types.OrderLine{0, 5, 10}}},
+		{"cage", []types.Constraint{types.SumCage{Group: []int{0, 1, 4}, Sum: 7}}},
+		{"mixed", []types.Constraint{
+			types.Relation{A: 3, B: 6, Kind: types.Double},
+			types.SumCage{Group: []int{14, 15}, Sum: 5},
+			types.OrderLine{12, 9},
+			types.AllDifferent{0, 10},
+		}},
+	} {
+		want := bruteForce(tc.rules)
+		if want == 0 {
+			t.Fatalf("%s: no grid keeps the rules, pick others", tc.name)
+		}
+
+		// Bans left behind by a dead end would hide later solutions, so
+		// a full count shows they are lifted on backtrack
+		s := ruleSearch(t, tc.rules)
+		if got := s.run(1000); got != want {
+			t.Errorf("%s: backtracking counts %d solutions, want %d", tc.name, got, want)
+		}
+		if len(s.trail) != 0 || len(s.bans) != 0 {
+			t.Errorf("%s: run left %d assignments and %d bans", tc.name, len(s.trail), len(s.bans))
+		}
+		for idx, mask := range s.banned {
+			if mask != 0 {
+				t.Errorf("%s: run left digits %b banned in cell %d", tc.name, mask, idx)
+			}
+		}
+
+		d, err := ruleSearch(t, tc.rules).dancer()
+		if len(s.rules) > 0 {
+			if err == nil {
+				t.Errorf("%s: dlx accepted rules it cannot cover", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := d.run(1000); got != want {
+			t.Errorf("%s: dlx counts %d solutions, want %d", tc.name, got, want)
+		}
+	}
+}
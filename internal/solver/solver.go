@@ -0,0 +1,567 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sudoku_gen_go/internal/types"
+)
+
+var (
+	// ErrInvalidGrid is returned when the grid is malformed or its givens
+	// conflict. Solve wraps it in ErrNoSolution, since such a grid has none.
+	ErrInvalidGrid = errors.New("invalid grid")
+	// ErrNoSolution is returned when the puzzle cannot be solved
+	ErrNoSolution = errors.New("puzzle has no solution")
+	// ErrStopped is returned when Options.Stop asked the search to give up
+	ErrStopped = errors.New("search stopped")
+	// ErrNodeLimit is returned when the search exceeded Options.NodeLimit
+	ErrNodeLimit = errors.New("search node limit exceeded")
+	// ErrUnsupported is returned when a backend cannot handle the rules of
+	// a variant
+	ErrUnsupported = errors.New("variant not supported by this solver")
+)
+
+// maxSize is the largest grid the bitmask core can represent
+const maxSize = 63
+
+// Options tunes a single solver run
+type Options struct {
+	// Shuffle reorders the alternatives tried at each branch. Leaving it
+	// nil keeps a fixed order, which makes solving deterministic.
+	Shuffle func(alternatives []int)
+	// Stop is polled during the search. Returning true aborts the run with
+	// ErrStopped.
+	Stop func() bool
+	// NodeLimit aborts the run with ErrNodeLimit after this many branches.
+	// Zero means no limit.
+	NodeLimit int
+}
+
+// Solver is a solving backend. Both methods leave the grid unchanged.
+type Solver interface {
+	// Solve returns a solution of grid.Puzzle
+	Solve(grid *types.Grid, opts Options) ([][]int, error)
+	// CountSolutions counts solutions of grid.Puzzle up to limit
+	CountSolutions(grid *types.Grid, limit int, opts Options) (int, error)
+}
+
+// Default is the backend used by the package-level functions
+var Default Solver = Backtracking{}
+
+// ByName returns the backend called name ("backtracking" or "dlx")
+func ByName(name string) (Solver, error) {
+	switch name {
+	case "backtracking":
+		return Backtracking{}, nil
+	case "dlx":
+		return DLX{}, nil
+	default:
+		return nil, fmt.Errorf("unknown solver backend %q", name)
+	}
+}
+
+// Solve returns a solution of grid.Puzzle without modifying the grid
+func Solve(grid *types.Grid) ([][]int, error) {
+	return SolveWith(grid, Options{})
+}
+
+// SolveWith is Solve with explicit options
+func SolveWith(grid *types.Grid, opts Options) ([][]int, error) {
+	return Default.Solve(grid, opts)
+}
+
+// CountSolutions counts the solutions of grid.Puzzle, stopping as soon as
+// limit solutions have been found. Invalid grids have no solutions.
+func CountSolutions(grid *types.Grid, limit int) int {
+	count, _ := CountSolutionsWith(grid, limit, Options{})
+	return count
+}
+
+// CountSolutionsWith is CountSolutions with explicit options. Unlike
+// CountSolutions it reports invalid grids and aborted searches as errors.
+func CountSolutionsWith(grid *types.Grid, limit int, opts Options) (int, error) {
+	return Default.CountSolutions(grid, limit, opts)
+}
+
+// Backtracking is the constraint-propagating backtracking backend
+type Backtracking struct{}
+
+// Solve implements Solver
+func (Backtracking) Solve(grid *types.Grid, opts Options) ([][]int, error) {
+	s, err := newSearch(grid, opts)
+	if err != nil {
+		return nil, unsolvable(err)
+	}
+
+	count := s.run(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if count == 0 {
+		return nil, ErrNoSolution
+	}
+	return toRows(s.size, s.solution), nil
+}
+
+// CountSolutions implements Solver
+func (Backtracking) CountSolutions(grid *types.Grid, limit int, opts Options) (int, error) {
+	s, err := newSearch(grid, opts)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	count := s.run(limit)
+	if s.err != nil {
+		return 0, s.err
+	}
+	return count, nil
+}
+
+// unsolvable wraps the errors of invalid grids in ErrNoSolution, so Solve
+// callers can check for a single error
+func unsolvable(err error) error {
+	if errors.Is(err, ErrInvalidGrid) {
+		return fmt.Errorf("%w: %w", ErrNoSolution, err)
+	}
+	return err
+}
+
+// search holds the working state of a single solver run. Every house (row,
+// column or region) keeps a bitmask of the digits already placed in it, so
+// the candidates of a cell are the digits missing from its row, column and
+// region. The houses of each cell are looked up once when the search is set
+// up.
+//
+// The all-different rules of a variant become extra houses. Its other rules
+// ban digits from cells as propagation finds them impossible; bans are
+// trailed like assignments and lifted again on undo. A rule is only run
+// again once the candidates of its cells differ from what it last left.
+type search struct {
+	size       int
+	full       uint64
+	cells      []int
+	houses     [][]int
+	cellHouses [][3]int
+	extra      [][]int // houses of each cell beyond its row, column and region
+	used       []uint64
+	rules      []types.Constraint
+	restricted [][]uint64 // candidates each rule last left, nil before its first run
+	banned     []uint64
+	trail      []int
+	bans       []ban
+	solution   []int
+	opts       Options
+	nodes      int
+	err        error
+}
+
+// ban records digits a rule removed from a cell
+type ban struct {
+	idx  int
+	mask uint64
+}
+
+// mark is a point in the trails that undo can return to
+type mark struct {
+	trail, bans int
+}
+
+func newSearch(grid *types.Grid, opts Options) (*search, error) {
+	size := grid.Size
+	if size <= 0 || size > maxSize || len(grid.Puzzle) != size {
+		return nil, fmt.Errorf("%w: puzzle must have between 1 and %d rows", ErrInvalidGrid, maxSize)
+	}
+
+	regions := grid.SubGrids
+	if len(regions) == 0 {
+		if grid.BoxWidth <= 0 || grid.BoxHeight <= 0 || grid.BoxWidth*grid.BoxHeight != size {
+			return nil, fmt.Errorf("%w: no regions and no usable box dimensions", ErrInvalidGrid)
+		}
+		regions = types.BoxRegions(size, grid.BoxWidth, grid.BoxHeight)
+	}
+
+	s := &search{
+		size:       size,
+		full:       uint64(1)<<(size+1) - 2,
+		cells:      make([]int, size*size),
+		cellHouses: make([][3]int, size*size),
+		extra:      make([][]int, size*size),
+		banned:     make([]uint64, size*size),
+		opts:       opts,
+	}
+
+	for i := 0; i < size; i++ {
+		row := make([]int, size)
+		col := make([]int, size)
+		for j := 0; j < size; j++ {
+			row[j] = i*size + j
+			col[j] = j*size + i
+		}
+		s.addHouse(0, row)
+		s.addHouse(1, col)
+	}
+
+	if err := types.CheckRegions(size, regions); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGrid, err)
+	}
+	for _, region := range regions {
+		s.addHouse(2, region)
+	}
+
+	if err := grid.CheckVariants(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGrid, err)
+	}
+	for _, rule := range grid.Constraints() {
+		cells := rule.Cells()
+		for _, idx := range cells {
+			if idx < 0 || idx >= size*size {
+				return nil, fmt.Errorf("%w: %v has invalid cell %d", ErrInvalidGrid, rule, idx)
+			}
+		}
+		group, ok := rule.(types.AllDifferent)
+		if !ok {
+			s.rules = append(s.rules, rule)
+			s.restricted = append(s.restricted, nil)
+			continue
+		}
+		if len(group) > size {
+			return nil, fmt.Errorf("%w: %v has more cells than digits", ErrInvalidGrid, rule)
+		}
+		h := len(s.houses)
+		s.houses = append(s.houses, group)
+		for _, idx := range group {
+			s.extra[idx] = append(s.extra[idx], h)
+		}
+	}
+	s.used = make([]uint64, len(s.houses))
+
+	for row := 0; row < size; row++ {
+		if len(grid.Puzzle[row]) != size {
+			return nil, fmt.Errorf("%w: row %d must have %d cells", ErrInvalidGrid, row, size)
+		}
+		for col := 0; col < size; col++ {
+			num := grid.Puzzle[row][col]
+			if num == 0 {
+				continue
+			}
+			idx := row*size + col
+			if num < 0 || num > size || s.candidates(idx)&(1<<num) == 0 {
+				return nil, fmt.Errorf("%w: conflicting value %d at row %d, column %d", ErrInvalidGrid, num, row+1, col+1)
+			}
+			s.assign(idx, num)
+		}
+	}
+	s.trail = s.trail[:0]
+
+	return s, nil
+}
+
+// addHouse registers a row (kind 0), column (kind 1) or region (kind 2)
+func (s *search) addHouse(kind int, cells []int) {
+	h := len(s.houses)
+	s.houses = append(s.houses, cells)
+	for _, idx := range cells {
+		s.cellHouses[idx][kind] = h
+	}
+}
+
+// candidates returns the bitmask of digits that may still go into idx
+func (s *search) candidates(idx int) uint64 {
+	h := &s.cellHouses[idx]
+	used := s.used[h[0]] | s.used[h[1]] | s.used[h[2]] | s.banned[idx]
+	for _, e := range s.extra[idx] {
+		used |= s.used[e]
+	}
+	return s.full &^ used
+}
+
+func (s *search) assign(idx, num int) {
+	s.cells[idx] = num
+	for _, h := range s.cellHouses[idx] {
+		s.used[h] |= 1 << num
+	}
+	for _, h := range s.extra[idx] {
+		s.used[h] |= 1 << num
+	}
+	s.trail = append(s.trail, idx)
+}
+
+// mark returns the current position in the trails
+func (s *search) mark() mark {
+	return mark{trail: len(s.trail), bans: len(s.bans)}
+}
+
+// undo clears every assignment and ban made after m
+func (s *search) undo(m mark) {
+	for _, idx := range s.trail[m.trail:] {
+		num := s.cells[idx]
+		for _, h := range s.cellHouses[idx] {
+			s.used[h] &^= 1 << num
+		}
+		for _, h := range s.extra[idx] {
+			s.used[h] &^= 1 << num
+		}
+		s.cells[idx] = 0
+	}
+	s.trail = s.trail[:m.trail]
+	for _, b := range s.bans[m.bans:] {
+		s.banned[b.idx] &^= b.mask
+	}
+	s.bans = s.bans[:m.bans]
+}
+
+// restrict lets every rule of the variant remove candidates, reporting
+// whether anything changed. ok is false when a rule can no longer be kept.
+func (s *search) restrict() (changed, ok bool) {
+	var cands []uint64
+	for r, rule := range s.rules {
+		cells := rule.Cells()
+		cands = cands[:0]
+		for _, idx := range cells {
+			if num := s.cells[idx]; num != 0 {
+				cands = append(cands, 1<<num)
+			} else {
+				cands = append(cands, s.candidates(idx))
+			}
+		}
+		if slices.Equal(cands, s.restricted[r]) {
+			continue
+		}
+		if !rule.Restrict(cands) {
+			return changed, false
+		}
+		s.restricted[r] = append(s.restricted[r][:0], cands...)
+		for i, idx := range cells {
+			if num := s.cells[idx]; num != 0 {
+				if cands[i]&(1<<num) == 0 {
+					return changed, false
+				}
+				continue
+			}
+			if removed := s.candidates(idx) &^ cands[i]; removed != 0 {
+				s.banned[idx] |= removed
+				s.bans = append(s.bans, ban{idx, removed})
+				changed = true
+			}
+		}
+	}
+	return changed, true
+}
+
+// propagate fills naked and hidden singles and applies the variant's rules
+// until nothing changes. It returns false when it runs into a
+// contradiction.
+func (s *search) propagate() bool {
+	for changed := true; changed; {
+		changed = false
+		if len(s.rules) > 0 {
+			var ok bool
+			if changed, ok = s.restrict(); !ok {
+				return false
+			}
+		}
+
+		for idx, num := range s.cells {
+			if num != 0 {
+				continue
+			}
+			cands := s.candidates(idx)
+			switch {
+			case cands == 0:
+				return false
+			case cands&(cands-1) == 0:
+				s.assign(idx, bits.TrailingZeros64(cands))
+				changed = true
+			}
+		}
+
+		for h, house := range s.houses {
+			if len(house) < s.size {
+				// Only complete houses must hold every digit
+				continue
+			}
+			var once, twice uint64
+			for _, idx := range house {
+				if s.cells[idx] == 0 {
+					cands := s.candidates(idx)
+					twice |= once & cands
+					once |= cands
+				}
+			}
+			missing := s.full &^ s.used[h]
+			if missing&^once != 0 {
+				return false
+			}
+			hidden := missing &^ twice
+			for hidden != 0 {
+				num := bits.TrailingZeros64(hidden)
+				hidden &= hidden - 1
+				for _, idx := range house {
+					if s.cells[idx] == 0 && s.candidates(idx)&(1<<num) != 0 {
+						s.assign(idx, num)
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// branch picks what to try next: either the empty cell with the fewest
+// candidates, or the digit with the fewest possible places in one house,
+// whichever offers fewer alternatives. It returns the cells paired with the
+// digits to try, or nil when the grid is full.
+func (s *search) branch() (cells, digits []int) {
+	best, bestCands, bestCount := -1, uint64(0), s.size+1
+	for idx, num := range s.cells {
+		if num != 0 {
+			continue
+		}
+		cands := s.candidates(idx)
+		if count := bits.OnesCount64(cands); count < bestCount {
+			best, bestCands, bestCount = idx, cands, count
+			if count <= 2 {
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return nil, nil
+	}
+
+	if bestCount > 2 {
+		house, num, places := s.fewestPlaces(bestCount)
+		if house >= 0 {
+			for _, idx := range s.houses[house] {
+				if s.cells[idx] == 0 && s.candidates(idx)&(1<<num) != 0 {
+					cells = append(cells, idx)
+					digits = append(digits, num)
+				}
+			}
+			if len(cells) == places {
+				s.shuffleTogether(cells, digits)
+				return cells, digits
+			}
+			cells, digits = cells[:0], digits[:0]
+		}
+	}
+
+	for ; bestCands != 0; bestCands &= bestCands - 1 {
+		cells = append(cells, best)
+		digits = append(digits, bits.TrailingZeros64(bestCands))
+	}
+	if s.opts.Shuffle != nil {
+		s.opts.Shuffle(digits)
+	}
+	return cells, digits
+}
+
+// fewestPlaces finds the house and missing digit with the fewest possible
+// places, if that is below limit
+func (s *search) fewestPlaces(limit int) (house, num, places int) {
+	house, places = -1, limit
+	counts := make([]int, s.size+1)
+	for h, cells := range s.houses {
+		if len(cells) < s.size {
+			continue
+		}
+		for i := range counts {
+			counts[i] = 0
+		}
+		for _, idx := range cells {
+			if s.cells[idx] != 0 {
+				continue
+			}
+			for cands := s.candidates(idx); cands != 0; cands &= cands - 1 {
+				counts[bits.TrailingZeros64(cands)]++
+			}
+		}
+		for n := 1; n <= s.size; n++ {
+			if s.used[h]&(1<<n) == 0 && counts[n] < places {
+				house, num, places = h, n, counts[n]
+			}
+		}
+	}
+	return house, num, places
+}
+
+// shuffleTogether applies Options.Shuffle to the order of paired branches
+func (s *search) shuffleTogether(cells, digits []int) {
+	if s.opts.Shuffle == nil {
+		return
+	}
+	order := make([]int, len(cells))
+	for i := range order {
+		order[i] = i
+	}
+	s.opts.Shuffle(order)
+	c := append([]int(nil), cells...)
+	d := append([]int(nil), digits...)
+	for i, o := range order {
+		cells[i], digits[i] = c[o], d[o]
+	}
+}
+
+// run counts solutions up to limit, remembering the first one found. It
+// leaves the grid as it found it.
+func (s *search) run(limit int) int {
+	if s.aborted() {
+		return 0
+	}
+
+	defer s.undo(s.mark())
+
+	if !s.propagate() {
+		return 0
+	}
+
+	cells, digits := s.branch()
+	if cells == nil {
+		if s.solution == nil {
+			s.solution = append([]int(nil), s.cells...)
+		}
+		return 1
+	}
+
+	count := 0
+	branch := s.mark()
+	for i, num := range digits {
+		s.assign(cells[i], num)
+		count += s.run(limit - count)
+		s.undo(branch)
+		if count >= limit || s.err != nil {
+			break
+		}
+	}
+	return count
+}
+
+func (s *search) aborted() bool {
+	if s.err != nil {
+		return true
+	}
+	s.nodes++
+	if s.opts.NodeLimit > 0 && s.nodes > s.opts.NodeLimit {
+		s.err = ErrNodeLimit
+	} else if s.opts.Stop != nil && s.opts.Stop() {
+		s.err = ErrStopped
+	}
+	return s.err != nil
+}
+
+// toRows splits a flat cell slice into grid rows
+func toRows(size int, cells []int) [][]int {
+	rows := make([][]int, size)
+	for row := range rows {
+		rows[row] = make([]int, size)
+		copy(rows[row], cells[row*size:(row+1)*size])
+	}
+	return rows
+}
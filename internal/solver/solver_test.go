@@ -0,0 +1,353 @@
+package solver_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// benchBoards lists the boards to benchmark and the share of cells dug out
+// of their puzzles. Uniqueness checks on 25x25 boards explode beyond about
+// half of the cells removed, so they are dug less.
+var benchBoards = []struct {
+	size, boxWidth, boxHeight int
+	removePercent             int
+}{
+	{9, 3, 3, 60},
+	{12, 3, 4, 60},
+	{16, 4, 4, 60},
+	{25, 5, 5, 45},
+}
+
+var backends = []struct {
+	name    string
+	backend solver.Solver
+}{
+	{"backtracking", solver.Backtracking{}},
+	{"dlx", solver.DLX{}},
+}
+
+// jigsawRegions morphs the boxes of a solved normal board into a jigsaw
+// layout by trading cells that hold the same digit between neighbouring
+// regions. Every region keeps all digits, so the layout stays solvable, and
+// swaps that would split a region are skipped.
+func jigsawRegions(b *testing.B, size, boxWidth, boxHeight int) [][]int {
+	rng := rand.New(rand.NewSource(1))
+	grid := benchGrid(b, size, boxWidth, boxHeight, types.Normal)
+	solution := fill(b, grid, rng)
+
+	owner := make([]int, size*size)
+	for r, region := range grid.SubGrids {
+		for _, idx := range region {
+			owner[idx] = r
+		}
+	}
+	digit := func(idx int) int { return solution[idx/size][idx%size] }
+
+	for swaps := 0; swaps < size*size; {
+		a := rng.Intn(size * size)
+		neighbours := adjacent(size, a)
+		other := owner[neighbours[rng.Intn(len(neighbours))]]
+		if other == owner[a] {
+			continue
+		}
+		for _, c := range rng.Perm(size * size) {
+			if owner[c] != other || digit(c) != digit(a) || !touches(size, owner, c, owner[a]) {
+				continue
+			}
+			owner[a], owner[c] = other, owner[a]
+			if connected(size, owner, owner[a]) && connected(size, owner, owner[c]) {
+				swaps++
+			} else {
+				owner[a], owner[c] = owner[c], owner[a]
+			}
+			break
+		}
+	}
+
+	regions := make([][]int, size)
+	for idx, r := range owner {
+		regions[r] = append(regions[r], idx)
+	}
+	return regions
+}
+
+func adjacent(size, idx int) []int {
+	row, col := idx/size, idx%size
+	var cells []int
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		r, c := row+d[0], col+d[1]
+		if r >= 0 && r < size && c >= 0 && c < size {
+			cells = append(cells, r*size+c)
+		}
+	}
+	return cells
+}
+
+func touches(size int, owner []int, idx, region int) bool {
+	for _, n := range adjacent(size, idx) {
+		if owner[n] == region {
+			return true
+		}
+	}
+	return false
+}
+
+func connected(size int, owner []int, region int) bool {
+	var start, total int
+	for idx, r := range owner {
+		if r == region {
+			start = idx
+			total++
+		}
+	}
+	seen := map[int]bool{start: true}
+	queue := []int{start}
+	for len(queue) > 0 {
+		idx := queue[0]
+		queue = queue[1:]
+		for _, n := range adjacent(size, idx) {
+			if owner[n] == region && !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return len(seen) == total
+}
+
+func benchGrid(b *testing.B, size, boxWidth, boxHeight int, typ types.SudokuType) *types.Grid {
+	grid := &types.Grid{Size: size, BoxWidth: boxWidth, BoxHeight: boxHeight, Type: typ}
+	grid.SubGrids = types.BoxRegions(size, boxWidth, boxHeight)
+	if typ == types.Jigsaw {
+		grid.SubGrids = jigsawRegions(b, size, boxWidth, boxHeight)
+	}
+	grid.Puzzle = make([][]int, size)
+	for i := range grid.Puzzle {
+		grid.Puzzle[i] = make([]int, size)
+	}
+	return grid
+}
+
+func shuffler(rng *rand.Rand) func([]int) {
+	return func(digits []int) {
+		rng.Shuffle(len(digits), func(i, j int) {
+			digits[i], digits[j] = digits[j], digits[i]
+		})
+	}
+}
+
+// fill solves an empty board with random digit order, restarting whenever a
+// try gets stuck in the heavy tail of the search
+func fill(b *testing.B, grid *types.Grid, rng *rand.Rand) [][]int {
+	for {
+		solution, err := solver.SolveWith(grid, solver.Options{
+			Shuffle:   shuffler(rng),
+			NodeLimit: grid.Size * grid.Size * 10,
+		})
+		if err == nil {
+			return solution
+		}
+		if !errors.Is(err, solver.ErrNodeLimit) {
+			b.Fatalf("fill %dx%d %s: %v", grid.Size, grid.Size, grid.Type, err)
+		}
+	}
+}
+
+// benchPuzzle fills a board and digs out removePercent of its cells while
+// keeping the solution unique
+func benchPuzzle(b *testing.B, size, boxWidth, boxHeight, removePercent int, typ types.SudokuType) *types.Grid {
+	rng := rand.New(rand.NewSource(1))
+	grid := benchGrid(b, size, boxWidth, boxHeight, typ)
+	grid.Puzzle = fill(b, grid, rng)
+
+	removed := 0
+	for _, idx := range rng.Perm(size * size) {
+		if removed >= size*size*removePercent/100 {
+			break
+		}
+		row, col := idx/size, idx%size
+		value := grid.Puzzle[row][col]
+		grid.Puzzle[row][col] = 0
+		if count, _ := (solver.DLX{}).CountSolutions(grid, 2, solver.Options{}); count != 1 {
+			grid.Puzzle[row][col] = value
+			continue
+		}
+		removed++
+	}
+	return grid
+}
+
+func BenchmarkFill(b *testing.B) {
+	for _, board := range benchBoards {
+		for _, typ := range []types.SudokuType{types.Normal, types.Jigsaw} {
+			b.Run(fmt.Sprintf("%d/%s", board.size, typ), func(b *testing.B) {
+				grid := benchGrid(b, board.size, board.boxWidth, board.boxHeight, typ)
+				rng := rand.New(rand.NewSource(1))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					fill(b, grid, rng)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCountSolutions(b *testing.B) {
+	for _, board := range benchBoards {
+		for _, typ := range []types.SudokuType{types.Normal, types.Jigsaw} {
+			grid := benchPuzzle(b, board.size, board.boxWidth, board.boxHeight, board.removePercent, typ)
+			for _, backend := range backends {
+				b.Run(fmt.Sprintf("%s/%d/%s", backend.name, board.size, typ), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						count, err := backend.backend.CountSolutions(grid, 2, solver.Options{})
+						if err != nil || count != 1 {
+							b.Fatalf("expected a unique puzzle, got %d solutions (%v)", count, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// knownPuzzles have a unique solution worked out by hand or by another
+// solver
+var knownPuzzles = []struct {
+	name     string
+	grid     *types.Grid
+	solution [][]int
+}{
+	{"classic", &types.Grid{
+		Size: 9, BoxWidth: 3, BoxHeight: 3, Type: types.Normal,
+		SubGrids: types.BoxRegions(9, 3, 3),
+		Puzzle: [][]int{
+			{5, 3, 0, 0, 7, 0, 0, 0, 0},
+			{6, 0, 0, 1, 9, 5, 0, 0, 0},
+			{0, 9, 8, 0, 0, 0, 0, 6, 0},
+			{8, 0, 0, 0, 6, 0, 0, 0, 3},
+			{4, 0, 0, 8, 0, 3, 0, 0, 1},
+			{7, 0, 0, 0, 2, 0, 0, 0, 6},
+			{0, 6, 0, 0, 0, 0, 2, 8, 0},
+			{0, 0, 0, 4, 1, 9, 0, 0, 5},
+			{0, 0, 0, 0, 8, 0, 0, 7, 9},
+		},
+	}, [][]int{
+		{5, 3, 4, 6, 7, 8, 9, 1, 2},
+		{6, 7, 2, 1, 9, 5, 3, 4, 8},
+		{1, 9, 8, 3, 4, 2, 5, 6, 7},
+		{8, 5, 9, 7, 6, 1, 4, 2, 3},
+		{4, 2, 6, 8, 5, 3, 7, 9, 1},
+		{7, 1, 3, 9, 2, 4, 8, 5, 6},
+		{9, 6, 1, 5, 3, 7, 2, 8, 4},
+		{2, 8, 7, 4, 1, 9, 6, 3, 5},
+		{3, 4, 5, 2, 8, 6, 1, 7, 9},
+	}},
+	{"jigsaw", &types.Grid{
+		Size: 6, Type: types.Jigsaw,
+		SubGrids: [][]int{
+			{34, 33, 35, 28, 32, 29},
+			{0, 1, 7, 8, 6, 9},
+			{30, 24, 25, 31, 19, 20},
+			{13, 14, 15, 12, 18, 16},
+			{26, 27, 21, 22, 23, 17},
+			{5, 4, 11, 10, 3, 2},
+		},
+		Puzzle: [][]int{
+			{0, 0, 0, 0, 5, 3},
+			{3, 0, 0, 4, 6, 1},
+			{0, 6, 1, 0, 0, 0},
+			{0, 3, 0, 0, 4, 0},
+			{0, 0, 3, 6, 0, 4},
+			{1, 4, 5, 3, 2, 0},
+		},
+	}, [][]int{
+		{6, 1, 4, 2, 5, 3},
+		{3, 5, 2, 4, 6, 1},
+		{4, 6, 1, 5, 3, 2},
+		{2, 3, 6, 1, 4, 5},
+		{5, 2, 3, 6, 1, 4},
+		{1, 4, 5, 3, 2, 6},
+	}},
+}
+
+// emptyGrid returns a 4x4 grid with no givens, which has 288 solutions
+func emptyGrid() *types.Grid {
+	grid := &types.Grid{Size: 4, BoxWidth: 2, BoxHeight: 2, Type: types.Normal}
+	grid.Puzzle = make([][]int, 4)
+	for i := range grid.Puzzle {
+		grid.Puzzle[i] = make([]int, 4)
+	}
+	return grid
+}
+
+func TestSolveKnownPuzzles(t *testing.T) {
+	for _, backend := range backends {
+		for _, tc := range knownPuzzles {
+			before := tc.grid.Clone()
+			solution, err := backend.backend.Solve(tc.grid, solver.Options{})
+			if err != nil {
+				t.Fatalf("%s, %s: %v", backend.name, tc.name, err)
+			}
+			if !reflect.DeepEqual(solution, tc.solution) {
+				t.Errorf("%s, %s: solved to %v, want %v", backend.name, tc.name, solution, tc.solution)
+			}
+			if count, err := backend.backend.CountSolutions(tc.grid, 2, solver.Options{}); count != 1 || err != nil {
+				t.Errorf("%s, %s: %d solutions (%v), want 1", backend.name, tc.name, count, err)
+			}
+			if !reflect.DeepEqual(tc.grid, before) {
+				t.Errorf("%s, %s: solving changed the grid", backend.name, tc.name)
+			}
+		}
+	}
+}
+
+func TestCountSolutionsStopsAtLimit(t *testing.T) {
+	for _, backend := range backends {
+		for _, tc := range []struct{ limit, want int }{
+			{1, 1},
+			{10, 10},
+			{288, 288},
+			{1000, 288},
+			{0, 0},
+		} {
+			count, err := backend.backend.CountSolutions(emptyGrid(), tc.limit, solver.Options{})
+			if err != nil || count != tc.want {
+				t.Errorf("%s: limit %d counted %d (%v), want %d", backend.name, tc.limit, count, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestSolveBadGrids(t *testing.T) {
+	conflict := emptyGrid()
+	conflict.Puzzle[0][0], conflict.Puzzle[0][3] = 2, 2
+	// The first cell has no digit left, though no givens clash
+	stuck := emptyGrid()
+	stuck.Puzzle[0][1], stuck.Puzzle[0][2] = 2, 3
+	stuck.Puzzle[2][0], stuck.Puzzle[3][0] = 1, 4
+	short := emptyGrid()
+	short.Puzzle[2] = short.Puzzle[2][:3]
+	badRegions := emptyGrid()
+	badRegions.SubGrids = [][]int{{0, 1, 2, 3}}
+
+	for _, backend := range backends {
+		for name, grid := range map[string]*types.Grid{
+			"conflicting givens": conflict,
+			"no candidates":      stuck,
+			"short row":          short,
+			"missing regions":    badRegions,
+		} {
+			if _, err := backend.backend.Solve(grid, solver.Options{}); !errors.Is(err, solver.ErrNoSolution) {
+				t.Errorf("%s, %s: got %v, want ErrNoSolution", backend.name, name, err)
+			}
+			if count, _ := backend.backend.CountSolutions(grid, 2, solver.Options{}); count != 0 {
+				t.Errorf("%s, %s: counted %d solutions", backend.name, name, count)
+			}
+		}
+	}
+}
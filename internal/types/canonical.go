@@ -0,0 +1,495 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// maxCanonicalPerms bounds how many column arrangements Canonical tries.
+// Refinement leaves only a handful for most puzzles; puzzles with many
+// symmetries of their own, such as nearly empty ones, can go beyond it.
+const maxCanonicalPerms = 100000
+
+// ErrNoCanonicalForm is returned for grids Canonical cannot handle
+var ErrNoCanonicalForm = errors.New("no canonical form")
+
+// Canonical returns the representative of the puzzle's isomorph class, so
+// two puzzles are the same for a player exactly when their canonical forms
+// are equal. The class covers digit relabeling, row swaps within a band,
+// column swaps within a stack, band and stack swaps, and for square boxes
+// transposition, which with the others gives rotations and reflections.
+// Rectangular boxes such as the 3x4 boxes of 12x12 grids do not survive a
+// transposition, so it is left out for them.
+//
+// Rows and columns are first coloured by how the givens around them look,
+// in a way that does not depend on the order of rows and columns or on the
+// names of the digits, see refine. Of the arrangements that put bands,
+// stacks and the lines within them in colour order, the representative is the smallest puzzle, read row by row with empty cells
+// as 0, after relabeling digits in order of first appearance. Only the
+// givens are compared; the result has no solution. Jigsaw grids, grids
+// with variants and grids with more than maxCanonicalPerms arrangements
+// left return ErrNoCanonicalForm.
+func (g *Grid) Canonical() (*Grid, error) {
+	cells, err := g.canonicalCells()
+	if err != nil {
+		return nil, err
+	}
+	grid := &Grid{
+		Size:      g.Size,
+		BoxWidth:  g.BoxWidth,
+		BoxHeight: g.BoxHeight,
+		Puzzle:    make([][]int, g.Size),
+		SubGrids:  BoxRegions(g.Size, g.BoxWidth, g.BoxHeight),
+		Type:      Normal,
+	}
+	for i := range grid.Puzzle {
+		grid.Puzzle[i] = cells[i*g.Size : (i+1)*g.Size]
+	}
+	return grid, nil
+}
+
+// Fingerprint returns a hash of the canonical form, the same for every
+// isomorph of the puzzle
+func (g *Grid) Fingerprint() (string, error) {
+	cells, err := g.canonicalCells()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%dx%d", g.BoxWidth, g.BoxHeight)
+	for _, num := range cells {
+		h.Write([]byte{byte(num)})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// canonicalCells returns the canonical puzzle flattened row by row
+func (g *Grid) canonicalCells() ([]int, error) {
+	size, w, h := g.Size, g.BoxWidth, g.BoxHeight
+	if g.Type == Jigsaw {
+		return nil, fmt.Errorf("%w: jigsaw regions have no fixed symmetries", ErrNoCanonicalForm)
+	}
+	if len(g.Variants) > 0 {
+		return nil, fmt.Errorf("%w: variants rule out most symmetries", ErrNoCanonicalForm)
+	}
+	if w < 1 || h < 1 || w*h != size || len(g.Puzzle) != size {
+		return nil, fmt.Errorf("%w: %dx%d boxes do not tile a %dx%d grid", ErrNoCanonicalForm, w, h, size, size)
+	}
+
+	for _, row := range g.Puzzle {
+		if len(row) != size {
+			return nil, fmt.Errorf("%w: rows must have %d cells", ErrNoCanonicalForm, size)
+		}
+		for _, num := range row {
+			if num < 0 || num > size {
+				return nil, fmt.Errorf("%w: invalid digit %d", ErrNoCanonicalForm, num)
+			}
+		}
+	}
+
+	orientations := [][][]int{g.Puzzle}
+	if w == h {
+		orientations = append(orientations, transpose(g.Puzzle))
+	}
+	arrangements := make([][][]int, len(orientations))
+	rowColors := make([][]int, len(orientations))
+	total := 0
+	for i, puzzle := range orientations {
+		rows, cols := refine(puzzle, w, h)
+		rowColors[i] = rows
+		var count int
+		arrangements[i], count = columnOrders(cols, w)
+		if total += count; total > maxCanonicalPerms {
+			return nil, fmt.Errorf("%w: the puzzle has too many symmetries", ErrNoCanonicalForm)
+		}
+	}
+
+	c := &canonicalizer{
+		size:  size,
+		band:  h,
+		best:  make([]int, size*size),
+		cur:   make([]int, size*size),
+		label: make([]int, size+1),
+		used:  make([]bool, size),
+		bands: make([]int, size/h),
+		fresh: make([]int, size+1),
+		grid:  make([][]int, size),
+	}
+	for i := range c.grid {
+		c.grid[i] = make([]int, size)
+	}
+	for i, puzzle := range orientations {
+		c.rows, c.bandColors = rowColors[i], groupColors(rowColors[i], h)
+		for _, perm := range arrangements[i] {
+			for r, row := range puzzle {
+				for col, from := range perm {
+					c.grid[r][col] = row[from]
+				}
+			}
+			c.search(0, true)
+		}
+	}
+	return c.best, nil
+}
+
+// canonicalizer picks rows for one column arrangement, keeping the
+// smallest puzzle found over all arrangements in best
+type canonicalizer struct {
+	size, band int
+	grid       [][]int // puzzle with the current column arrangement
+	rows       []int   // row colours from refine
+	bandColors []int
+	best, cur  []int
+	found      bool
+	updates    int   // bumped whenever best changes
+	label      []int // digit to its label in cur, 0 if not seen yet
+	next       int   // next free label
+	used       []bool
+	bands      []int // original band of each band slot filled so far
+	fresh      []int // scratch labels for relabel
+}
+
+// search fills row pos of cur. Bands and the rows within them go in
+// colour order, and of the rows whose colour comes next only those whose
+// relabeled form is the smallest possible can lead to the minimum, so it
+// branches on those alone. eq means cur[:pos] equals best[:pos], so rows must not be larger
+// than best's.
+func (c *canonicalizer) search(pos int, eq bool) {
+	size := c.size
+	if pos == size {
+		copy(c.best, c.cur)
+		c.found = true
+		c.updates++
+		return
+	}
+
+	// Rows of the band started at pos, or at a band's first row of any
+	// unused band of the lowest colour left, keeping the lowest row colour
+	var candidates []int
+	if pos%c.band == 0 {
+		lowest := -1
+		for r := 0; r < size; r++ {
+			if color := c.bandColors[r/c.band]; !c.used[r] && (lowest < 0 || color < lowest) {
+				lowest = color
+			}
+		}
+		for r := 0; r < size; r++ {
+			if !c.used[r] && c.bandColors[r/c.band] == lowest {
+				candidates = append(candidates, r)
+			}
+		}
+	} else {
+		band := c.bands[pos/c.band]
+		for r := band * c.band; r < (band+1)*c.band; r++ {
+			if !c.used[r] {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+	lowest := -1
+	for _, r := range candidates {
+		if lowest < 0 || c.rows[r] < lowest {
+			lowest = c.rows[r]
+		}
+	}
+	candidates = slices.DeleteFunc(candidates, func(r int) bool { return c.rows[r] != lowest })
+
+	var minRow []int
+	var ties []int
+	row := make([]int, size)
+	for _, r := range candidates {
+		c.relabel(c.grid[r], row)
+		switch cmp := slices.Compare(row, minRow); {
+		case minRow == nil || cmp < 0:
+			minRow = slices.Clone(row)
+			ties = append(ties[:0], r)
+		case cmp == 0:
+			ties = append(ties, r)
+		}
+	}
+
+	target := c.cur[pos*size : (pos+1)*size]
+	copy(target, minRow)
+	for i, r := range ties {
+		// Equal rows of one band lead to the same puzzles, so only the
+		// first is tried
+		if slices.ContainsFunc(ties[:i], func(t int) bool {
+			return t/c.band == r/c.band && slices.Equal(c.grid[t], c.grid[r])
+		}) {
+			continue
+		}
+
+		childEq := false
+		if c.found && eq {
+			cmp := slices.Compare(minRow, c.best[pos*size:(pos+1)*size])
+			if cmp > 0 {
+				return
+			}
+			childEq = cmp == 0
+		}
+
+		c.used[r] = true
+		c.bands[pos/c.band] = r / c.band
+		fresh := c.assign(c.grid[r])
+		before := c.updates
+		c.search(pos+1, childEq)
+		for _, digit := range fresh {
+			c.label[digit] = 0
+		}
+		c.next -= len(fresh)
+		c.used[r] = false
+
+		if c.updates != before {
+			// best now starts with cur[:pos+1]
+			eq = true
+		}
+	}
+}
+
+// relabel writes row with labels applied into out, giving unseen digits
+// the labels they would get next without assigning them
+func (c *canonicalizer) relabel(row, out []int) {
+	next := c.next
+	for i, num := range row {
+		switch {
+		case num == 0:
+			out[i] = 0
+		case c.label[num] != 0:
+			out[i] = c.label[num]
+		default:
+			if c.fresh[num] == 0 {
+				next++
+				c.fresh[num] = next
+			}
+			out[i] = c.fresh[num]
+		}
+	}
+	for _, num := range row {
+		c.fresh[num] = 0
+	}
+}
+
+// assign gives the unseen digits of row their labels and returns them so
+// the caller can undo it
+func (c *canonicalizer) assign(row []int) []int {
+	var fresh []int
+	for _, num := range row {
+		if num != 0 && c.label[num] == 0 {
+			c.next++
+			c.label[num] = c.next
+			fresh = append(fresh, num)
+		}
+	}
+	return fresh
+}
+
+// refine colours the rows and columns of the puzzle, and its digits, by
+// what the givens around them look like. A colour is the rank of a line's
+// signature among all lines: its previous colour, the colour of its band
+// or stack, and the colours of the column, stack and digit of each of its
+// givens. Digits are coloured by the lines they are given in. Rounds go
+// on until no colour class splits. Only the structure of the puzzle goes
+// in, so an isomorphism maps every line onto a line of the same colour.
+func refine(puzzle [][]int, w, h int) (rows, cols []int) {
+	size := len(puzzle)
+	rows, cols = make([]int, size), make([]int, size)
+	digits := make([]int, size+1)
+	// Colours stay below size+1, so base packs a tuple of them in an int
+	base := size + 2
+
+	classes := 0
+	for {
+		bands, stacks := groupColors(rows, h), groupColors(cols, w)
+		newRows, nr := rank(size, func(r int) []int {
+			var sig []int
+			for c, num := range puzzle[r] {
+				if num != 0 {
+					sig = append(sig, (cols[c]*base+stacks[c/w])*base+digits[num])
+				}
+			}
+			slices.Sort(sig)
+			return append([]int{rows[r], bands[r/h]}, sig...)
+		})
+		newCols, nc := rank(size, func(c int) []int {
+			var sig []int
+			for r := range puzzle {
+				if num := puzzle[r][c]; num != 0 {
+					sig = append(sig, (rows[r]*base+bands[r/h])*base+digits[num])
+				}
+			}
+			slices.Sort(sig)
+			return append([]int{cols[c], stacks[c/w]}, sig...)
+		})
+		newDigits, nd := rank(size+1, func(d int) []int {
+			var sig []int
+			for r, row := range puzzle {
+				for c, num := range row {
+					if num == d && d != 0 {
+						sig = append(sig, rows[r]*base+cols[c])
+					}
+				}
+			}
+			slices.Sort(sig)
+			return append([]int{digits[d]}, sig...)
+		})
+		rows, cols, digits = newRows, newCols, newDigits
+		if nr+nc+nd == classes {
+			return rows, cols
+		}
+		classes = nr + nc + nd
+	}
+}
+
+// rank colours n items by the rank of their signature, equal signatures
+// getting equal colours, and returns the number of colours
+func rank(n int, signature func(i int) []int) ([]int, int) {
+	sigs := make([][]int, n)
+	order := make([]int, n)
+	for i := range sigs {
+		sigs[i] = signature(i)
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int { return slices.Compare(sigs[a], sigs[b]) })
+	colors := make([]int, n)
+	color := 0
+	for k, i := range order {
+		if k > 0 && slices.Compare(sigs[order[k-1]], sigs[i]) != 0 {
+			color++
+		}
+		colors[i] = color
+	}
+	return colors, color + 1
+}
+
+// groupColors colours each group of width consecutive lines, a band or a
+// stack, by the sorted colours of its lines
+func groupColors(colors []int, width int) []int {
+	groups, _ := rank(len(colors)/width, func(g int) []int {
+		sig := slices.Clone(colors[g*width : (g+1)*width])
+		slices.Sort(sig)
+		return sig
+	})
+	return groups
+}
+
+// columnOrders returns the column arrangements that order the stacks of
+// width columns and the columns within each stack by colour, trying every
+// order of equal colours, and how many there are. It returns no
+// arrangements once there are more than maxCanonicalPerms.
+func columnOrders(cols []int, width int) ([][]int, int) {
+	stackColors := groupColors(cols, width)
+	stackGroups := colorClasses(identity(len(stackColors)), stackColors)
+	count := orderCount(stackGroups)
+	within := make([][][]int, len(stackColors))
+	for stack := range within {
+		lines := make([]int, width)
+		for i := range lines {
+			lines[i] = stack*width + i
+		}
+		groups := colorClasses(lines, cols)
+		if count *= orderCount(groups); count > maxCanonicalPerms {
+			return nil, maxCanonicalPerms + 1
+		}
+		within[stack] = orderings(groups)
+	}
+
+	var perms [][]int
+	perm := make([]int, len(cols))
+	var build func(stackOrder []int, i int)
+	build = func(stackOrder []int, i int) {
+		if i == len(stackOrder) {
+			perms = append(perms, slices.Clone(perm))
+			return
+		}
+		for _, order := range within[stackOrder[i]] {
+			copy(perm[i*width:], order)
+			build(stackOrder, i+1)
+		}
+	}
+	for _, stackOrder := range orderings(stackGroups) {
+		build(stackOrder, 0)
+	}
+	return perms, count
+}
+
+// colorClasses sorts items by colour and splits them into runs of equal
+// colour
+func colorClasses(items, colors []int) [][]int {
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b int) int { return colors[a] - colors[b] })
+	var groups [][]int
+	for i, item := range sorted {
+		if i == 0 || colors[item] != colors[sorted[i-1]] {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], item)
+	}
+	return groups
+}
+
+// orderCount returns len(orderings(groups)), capped just above
+// maxCanonicalPerms
+func orderCount(groups [][]int) int {
+	count := 1
+	for _, group := range groups {
+		for i := 2; i <= len(group); i++ {
+			if count *= i; count > maxCanonicalPerms {
+				return maxCanonicalPerms + 1
+			}
+		}
+	}
+	return count
+}
+
+// orderings returns every concatenation of the groups, in their order,
+// with the items of each group in every order
+func orderings(groups [][]int) [][]int {
+	result := [][]int{{}}
+	for _, group := range groups {
+		var next [][]int
+		for _, prefix := range result {
+			for _, p := range permutations(len(group)) {
+				order := slices.Clone(prefix)
+				for _, i := range p {
+					order = append(order, group[i])
+				}
+				next = append(next, order)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// permutations returns every ordering of 0..n-1
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var perms [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			q := make([]int, 0, n)
+			q = append(q, p[:i]...)
+			q = append(q, n-1)
+			q = append(q, p[i:]...)
+			perms = append(perms, q)
+		}
+	}
+	return perms
+}
+
+// transpose returns the rows of cells as columns
+func transpose(cells [][]int) [][]int {
+	out := make([][]int, len(cells))
+	for i := range out {
+		out[i] = make([]int, len(cells))
+		for j := range out[i] {
+			out[i][j] = cells[j][i]
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package types_test
+
+import (
+	"errors"
+	"math/rand"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// patternGrid returns a size x size puzzle with the default boxes: a
+// patterned solution with a random half of its cells left as givens. It
+// need not have a unique solution, which Canonical does not look at.
+func patternGrid(size int, seed int64) *types.Grid {
+	grid := types.NewGrid(size, types.Normal)
+	grid.SubGrids = types.BoxRegions(size, grid.BoxWidth, grid.BoxHeight)
+	rng := rand.New(rand.NewSource(seed))
+	w, h := grid.BoxWidth, grid.BoxHeight
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			num := (w*(r%h)+r/h+c)%size + 1
+			grid.Solution[r][c] = num
+			if rng.Intn(2) == 0 {
+				grid.Puzzle[r][c] = num
+			}
+		}
+	}
+	return grid
+}
+
+// fingerprintGrids returns a puzzle of every box shape Canonical handles
+func fingerprintGrids() map[string]*types.Grid {
+	return map[string]*types.Grid{
+		"classic": classic,
+		"6x6":     patternGrid(6, 1),
+		"9x9":     patternGrid(9, 1),
+		"12x12":   patternGrid(12, 1),
+		"16x16":   patternGrid(16, 1),
+		"25x25":   patternGrid(25, 1),
+	}
+}
+
+func TestFingerprintSurvivesTransforms(t *testing.T) {
+	transforms := []struct {
+		name   string
+		square bool // only an isomorphism for square boxes
+		fn     func(*types.Grid) (*types.Grid, error)
+	}{
+		{"relabel", false, func(g *types.Grid) (*types.Grid, error) {
+			perm := make([]int, g.Size)
+			for i := range perm {
+				perm[i] = (i+3)%g.Size + 1
+			}
+			return g.RelabelDigits(perm)
+		}},
+		{"swap rows", false, func(g *types.Grid) (*types.Grid, error) { return g.SwapRows(0, 1) }},
+		{"swap columns", false, func(g *types.Grid) (*types.Grid, error) { return g.SwapColumns(1, 2) }},
+		{"swap bands", false, func(g *types.Grid) (*types.Grid, error) { return g.SwapBands(0, 1) }},
+		{"swap stacks", false, func(g *types.Grid) (*types.Grid, error) { return g.SwapStacks(0, 1) }},
+		{"flip horizontal", false, func(g *types.Grid) (*types.Grid, error) { return g.FlipHorizontal(), nil }},
+		{"flip vertical", false, func(g *types.Grid) (*types.Grid, error) { return g.FlipVertical(), nil }},
+		{"rotate 2", false, func(g *types.Grid) (*types.Grid, error) { return g.Rotate(2), nil }},
+		{"transpose", true, func(g *types.Grid) (*types.Grid, error) { return g.Transpose(), nil }},
+		{"rotate 1", true, func(g *types.Grid) (*types.Grid, error) { return g.Rotate(1), nil }},
+		{"rotate 3", true, func(g *types.Grid) (*types.Grid, error) { return g.Rotate(3), nil }},
+	}
+
+	for name, grid := range fingerprintGrids() {
+		want, err := grid.Fingerprint()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, tr := range transforms {
+			if tr.square && grid.BoxWidth != grid.BoxHeight {
+				continue
+			}
+			out, err := tr.fn(grid)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, tr.name, err)
+			}
+			if got, err := out.Fingerprint(); err != nil || got != want {
+				t.Errorf("%s, %s: fingerprint %s (%v), want %s", name, tr.name, got, err, want)
+			}
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < 5; i++ {
+			if got, err := grid.RandomIsomorph(rng).Fingerprint(); err != nil || got != want {
+				t.Errorf("%s, random isomorph: fingerprint %s (%v), want %s", name, got, err, want)
+			}
+		}
+	}
+}
+
+func TestCanonicalIsItsOwnForm(t *testing.T) {
+	for name, grid := range fingerprintGrids() {
+		canonical, err := grid.Canonical()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		again, err := canonical.Canonical()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for r := range canonical.Puzzle {
+			for c, num := range canonical.Puzzle[r] {
+				if again.Puzzle[r][c] != num {
+					t.Fatalf("%s: canonical form changes when canonicalised again", name)
+				}
+			}
+		}
+	}
+}
+
+func TestFingerprintTellsPuzzlesApart(t *testing.T) {
+	for name, grid := range fingerprintGrids() {
+		want, err := grid.Fingerprint()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// One more given makes a different puzzle
+		other := grid.Clone()
+		for c, num := range other.Puzzle[0] {
+			if num == 0 {
+				other.Puzzle[0][c] = other.Solution[0][c]
+				break
+			}
+		}
+		if got, err := other.Fingerprint(); err != nil || got == want {
+			t.Errorf("%s: extra given kept fingerprint %s (%v)", name, got, err)
+		}
+	}
+}
+
+func TestCanonicalRejects(t *testing.T) {
+	jigsaw := classic.Clone()
+	jigsaw.Type = types.Jigsaw
+	withVariant := classic.Clone()
+	withVariant.Variants = []types.SudokuType{types.X}
+	badDigit := classic.Clone()
+	badDigit.Puzzle[0][2] = 10
+	for name, grid := range map[string]*types.Grid{
+		"jigsaw":    jigsaw,
+		"variant":   withVariant,
+		"bad digit": badDigit,
+	} {
+		if _, err := grid.Fingerprint(); !errors.Is(err, types.ErrNoCanonicalForm) {
+			t.Errorf("%s: got %v, want ErrNoCanonicalForm", name, err)
+		}
+	}
+}
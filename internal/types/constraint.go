@@ -0,0 +1,351 @@
+package types
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Constraint is a rule a variant puts on a group of cells on top of the
+// rows, columns and regions every grid has. The solver, the uniqueness
+// check and the grader only see variants through this interface, so a new
+// variant needs no search code of its own.
+type Constraint interface {
+	// Cells returns the cells the rule covers, as row*Size+col indices
+	Cells() []int
+	// Restrict removes digits that cannot take part in any assignment that
+	// keeps the rule. cands holds one bitmask per cell of Cells, in the
+	// same order, with bit d set while digit d is still possible; a placed
+	// digit is a single bit. Restrict may leave digits it cannot rule out
+	// cheaply, but it must reject every complete assignment that breaks
+	// the rule. It returns false when the rule can no longer be kept.
+	Restrict(cands []uint64) bool
+}
+
+// Satisfies reports whether the digits in values, one per cell of
+// c.Cells(), keep the rule
+func Satisfies(c Constraint, values []int) bool {
+	cands := make([]uint64, len(values))
+	for i, num := range values {
+		if num < 1 || num > 63 {
+			return false
+		}
+		cands[i] = 1 << num
+	}
+	return c.Restrict(cands)
+}
+
+// Constraints returns the rules of the grid's variants beyond its rows,
+// columns and regions. Grids without variants have none.
+func (g *Grid) Constraints() []Constraint {
+	var rules []Constraint
+	for _, v := range g.Variants {
+		switch v {
+		case X:
+			for _, diagonal := range Diagonals(g.Size) {
+				rules = append(rules, AllDifferent(diagonal))
+			}
+		case Hyper:
+			// CheckVariants reports grids without a window layout
+			windows, _ := HyperWindows(g.Size, g.BoxWidth, g.BoxHeight)
+			for _, window := range windows {
+				rules = append(rules, AllDifferent(window))
+			}
+		case Killer:
+			for _, cage := range g.Cages {
+				rules = append(rules, SumCage{Group: cage.Cells, Sum: cage.Sum})
+			}
+		}
+	}
+	return rules
+}
+
+// CheckConstraints reports the first rule of the grid's variants that the
+// filled rows break
+func (g *Grid) CheckConstraints(rows [][]int) error {
+	for _, c := range g.Constraints() {
+		cells := c.Cells()
+		values := make([]int, len(cells))
+		for i, cell := range cells {
+			values[i] = rows[cell/g.Size][cell%g.Size]
+		}
+		if !Satisfies(c, values) {
+			return fmt.Errorf("%v breaks %v", values, c)
+		}
+	}
+	return nil
+}
+
+// AllDifferent forbids repeating a digit within its cells, like a row
+type AllDifferent []int
+
+// Cells implements Constraint
+func (a AllDifferent) Cells() []int { return a }
+
+// Restrict implements Constraint. It removes placed digits from the other
+// cells until nothing changes and checks that enough digits are left.
+func (a AllDifferent) Restrict(cands []uint64) bool {
+	for changed := true; changed; {
+		changed = false
+		var placed, union uint64
+		for _, c := range cands {
+			if c == 0 {
+				return false
+			}
+			if c&(c-1) == 0 {
+				if placed&c != 0 {
+					return false
+				}
+				placed |= c
+			}
+			union |= c
+		}
+		if bits.OnesCount64(union) < len(cands) {
+			return false
+		}
+		for i, c := range cands {
+			if c&(c-1) != 0 && c&placed != 0 {
+				cands[i] = c &^ placed
+				changed = true
+			}
+		}
+	}
+	return true
+}
+
+func (a AllDifferent) String() string {
+	return fmt.Sprintf("all different %v", []int(a))
+}
+
+// RelationKind says how the two digits of a Relation relate
+type RelationKind string
+
+const (
+	// Less means the digit in A is smaller than the one in B
+	Less RelationKind = "less"
+	// Consecutive means the digits differ by one
+	Consecutive RelationKind = "consecutive"
+	// Double means one digit is twice the other
+	Double RelationKind = "double"
+	// Different means the digits differ, for cells that share no house
+	Different RelationKind = "different"
+)
+
+// Relation ties the digits of two cells together
+type Relation struct {
+	A, B int
+	Kind RelationKind
+}
+
+// Cells implements Constraint
+func (r Relation) Cells() []int { return []int{r.A, r.B} }
+
+// Restrict implements Constraint. It keeps the digits of each cell that
+// have a partner in the other.
+func (r Relation) Restrict(cands []uint64) bool {
+	var a, b uint64
+	for x := cands[0]; x != 0; x &= x - 1 {
+		da := bits.TrailingZeros64(x)
+		for y := cands[1]; y != 0; y &= y - 1 {
+			db := bits.TrailingZeros64(y)
+			if r.holds(da, db) {
+				a |= 1 << da
+				b |= 1 << db
+			}
+		}
+	}
+	cands[0], cands[1] = a, b
+	return a != 0
+}
+
+func (r Relation) holds(a, b int) bool {
+	switch r.Kind {
+	case Less:
+		return a < b
+	case Consecutive:
+		return a-b == 1 || b-a == 1
+	case Double:
+		return a == 2*b || b == 2*a
+	case Different:
+		return a != b
+	}
+	return false
+}
+
+func (r Relation) String() string {
+	return fmt.Sprintf("%s relation of cells %d and %d", r.Kind, r.A, r.B)
+}
+
+// SumCage is a killer cage: its digits do not repeat and add up to Sum.
+// Restrict walks the ways to fill the cage, so cages should stay small.
+type SumCage struct {
+	Group []int
+	Sum   int
+}
+
+// Cells implements Constraint
+func (s SumCage) Cells() []int { return s.Group }
+
+// exactCageFills bounds the ways to fill a cage, counting every candidate
+// of every cell, up to which SumCage.Restrict walks them
+const exactCageFills = 1 << 12
+
+// Restrict implements Constraint. It keeps exactly the digits that appear
+// in some way to fill the cage. It first picks the sets of distinct digits
+// that add up to Sum, then places each set in the cells. Cages with more
+// ways to fill them than exactCageFills only get their sum bounds checked.
+func (s SumCage) Restrict(cands []uint64) bool {
+	n := len(cands)
+	fills := 1
+	for _, c := range cands {
+		if fills *= bits.OnesCount64(c); fills > exactCageFills {
+			return s.bound(cands)
+		}
+	}
+
+	supported := make([]uint64, n)
+	var all uint64
+	for _, c := range cands {
+		all |= c
+	}
+
+	// fits reports whether every cell can take a digit of the set and
+	// the cells can take all of them, before trying to place it
+	fits := func(set uint64) bool {
+		var union uint64
+		for _, c := range cands {
+			if c&set == 0 {
+				return false
+			}
+			union |= c & set
+		}
+		return union == set
+	}
+
+	// place tries every order of the set's digits in the cells from i on,
+	// marking the digits some order uses
+	var place func(set uint64, i int, used uint64) bool
+	place = func(set uint64, i int, used uint64) bool {
+		if i == n {
+			return true
+		}
+		found := false
+		for c := cands[i] & set &^ used; c != 0; c &= c - 1 {
+			num := bits.TrailingZeros64(c)
+			if place(set, i+1, used|1<<num) {
+				supported[i] |= 1 << num
+				found = true
+			}
+		}
+		return found
+	}
+
+	// pick chooses the remaining digits of a set from the digits in from,
+	// smallest first
+	var pick func(from uint64, count, sum int, set uint64)
+	pick = func(from uint64, count, sum int, set uint64) {
+		left := n - count
+		if left == 0 {
+			if sum == s.Sum && fits(set) {
+				place(set, 0, 0)
+			}
+			return
+		}
+		lo, hi, ok := sumRange(from, left)
+		if !ok || sum+lo > s.Sum || sum+hi < s.Sum {
+			return
+		}
+		num := bits.TrailingZeros64(from)
+		rest := from &^ (1 << num)
+		pick(rest, count+1, sum+num, set|1<<num)
+		pick(rest, count, sum, set)
+	}
+
+	pick(all, 0, 0, 0)
+	copy(cands, supported)
+	return n > 0 && supported[0] != 0
+}
+
+// bound keeps the digits of each cell that leave a remainder the other
+// cells could add up to with distinct digits of theirs
+func (s SumCage) bound(cands []uint64) bool {
+	for i, c := range cands {
+		var others uint64
+		for j, o := range cands {
+			if j != i {
+				others |= o
+			}
+		}
+		var kept uint64
+		for x := c; x != 0; x &= x - 1 {
+			num := bits.TrailingZeros64(x)
+			lo, hi, ok := sumRange(others&^(1<<num), len(cands)-1)
+			if ok && lo <= s.Sum-num && s.Sum-num <= hi {
+				kept |= 1 << num
+			}
+		}
+		if kept == 0 {
+			return false
+		}
+		cands[i] = kept
+	}
+	return true
+}
+
+// sumRange returns the smallest and largest sums of count distinct digits
+// from the mask. ok is false when it has fewer digits.
+func sumRange(digits uint64, count int) (lo, hi int, ok bool) {
+	if bits.OnesCount64(digits) < count {
+		return 0, 0, false
+	}
+	for k, low, high := 0, digits, digits; k < count; k++ {
+		lo += bits.TrailingZeros64(low)
+		low &= low - 1
+		top := 63 - bits.LeadingZeros64(high)
+		hi += top
+		high &^= 1 << top
+	}
+	return lo, hi, true
+}
+
+func (s SumCage) String() string {
+	return fmt.Sprintf("cage %v summing to %d", s.Group, s.Sum)
+}
+
+// OrderLine is a thermometer: digits strictly increase from its first cell
+// to its last
+type OrderLine []int
+
+// Cells implements Constraint
+func (o OrderLine) Cells() []int { return o }
+
+// Restrict implements Constraint. Each cell keeps the digits above the
+// smallest possible digit before it and below the largest possible digit
+// after it, which is exact for a single line.
+func (o OrderLine) Restrict(cands []uint64) bool {
+	floor := 0
+	for i, c := range cands {
+		c &^= uint64(1)<<(floor+1) - 1
+		if c == 0 {
+			return false
+		}
+		cands[i] = c
+		floor = bits.TrailingZeros64(c)
+	}
+	ceiling := 64
+	for i := len(cands) - 1; i >= 0; i-- {
+		c := cands[i]
+		if ceiling < 64 {
+			c &= uint64(1)<<ceiling - 1
+		}
+		if c == 0 {
+			return false
+		}
+		cands[i] = c
+		ceiling = 63 - bits.LeadingZeros64(c)
+	}
+	return true
+}
+
+func (o OrderLine) String() string {
+	return fmt.Sprintf("order line %v", []int(o))
+}
@@ -0,0 +1,240 @@
+package types_test
+
+import (
+	"math/rand"
+	"slices"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// digits returns the candidate mask holding the given digits
+func digits(nums ...int) uint64 {
+	var mask uint64
+	for _, num := range nums {
+		mask |= 1 << num
+	}
+	return mask
+}
+
+// span returns the candidate mask of the digits lo to hi
+func span(lo, hi int) uint64 {
+	var mask uint64
+	for num := lo; num <= hi; num++ {
+		mask |= 1 << num
+	}
+	return mask
+}
+
+func TestRestrict(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rule  types.Constraint
+		cands []uint64
+		want  []uint64 // nil when Restrict must fail
+	}{
+		{"all different chain", types.AllDifferent{0, 1, 2},
+			[]uint64{digits(1), digits(1, 2), span(1, 3)},
+			[]uint64{digits(1), digits(2), digits(3)}},
+		{"all different untouched", types.AllDifferent{0, 1},
+			[]uint64{digits(1, 2), digits(2, 3)},
+			[]uint64{digits(1, 2), digits(2, 3)}},
+		{"all different repeat", types.AllDifferent{0, 1},
+			[]uint64{digits(4), digits(4)}, nil},
+		{"all different empty cell", types.AllDifferent{0, 1},
+			[]uint64{digits(4), 0}, nil},
+		{"all different too few digits", types.AllDifferent{0, 1, 2},
+			[]uint64{digits(1, 2), digits(1, 2), digits(1, 2)}, nil},
+
+		{"less", types.Relation{A: 0, B: 1, Kind: types.Less},
+			[]uint64{span(1, 4), span(1, 4)},
+			[]uint64{span(1, 3), span(2, 4)}},
+		{"less impossible", types.Relation{A: 0, B: 1, Kind: types.Less},
+			[]uint64{digits(5), span(1, 5)}, nil},
+		{"consecutive", types.Relation{A: 0, B: 1, Kind: types.Consecutive},
+			[]uint64{digits(2), span(1, 9)},
+			[]uint64{digits(2), digits(1, 3)}},
+		{"double", types.Relation{A: 0, B: 1, Kind: types.Double},
+			[]uint64{span(1, 4), digits(4)},
+			[]uint64{digits(2), digits(4)}},
+		{"double impossible", types.Relation{A: 0, B: 1, Kind: types.Double},
+			[]uint64{span(1, 4), digits(3)}, nil},
+		{"different", types.Relation{A: 0, B: 1, Kind: types.Different},
+			[]uint64{digits(3), digits(3, 5)},
+			[]uint64{digits(3), digits(5)}},
+		{"different repeat", types.Relation{A: 0, B: 1, Kind: types.Different},
+			[]uint64{digits(3), digits(3)}, nil},
+
+		{"order line open", types.OrderLine{0, 1, 2},
+			[]uint64{span(1, 9), span(1, 9), span(1, 9)},
+			[]uint64{span(1, 7), span(2, 8), span(3, 9)}},
+		{"order line squeezed", types.OrderLine{0, 1, 2},
+			[]uint64{digits(2, 5), span(1, 9), digits(8, 9)},
+			[]uint64{digits(2, 5), span(3, 8), digits(8, 9)}},
+		{"order line impossible", types.OrderLine{0, 1, 2},
+			[]uint64{digits(5), span(1, 9), digits(6)}, nil},
+
+		{"cage smallest sum", types.SumCage{Group: []int{0, 1}, Sum: 3},
+			[]uint64{span(1, 9), span(1, 9)},
+			[]uint64{digits(1, 2), digits(1, 2)}},
+		{"cage largest sum", types.SumCage{Group: []int{0, 1}, Sum: 17},
+			[]uint64{span(1, 9), span(1, 9)},
+			[]uint64{digits(8, 9), digits(8, 9)}},
+		{"cage with a placed digit", types.SumCage{Group: []int{0, 1, 2}, Sum: 10},
+			[]uint64{span(1, 9), span(1, 9), digits(5)},
+			[]uint64{span(1, 4), span(1, 4), digits(5)}},
+		{"cage needs a repeat", types.SumCage{Group: []int{0, 1}, Sum: 2},
+			[]uint64{span(1, 9), span(1, 9)}, nil},
+		{"cage filled with a repeat", types.SumCage{Group: []int{0, 1}, Sum: 4},
+			[]uint64{digits(2), digits(2)}, nil},
+		{"cage filled", types.SumCage{Group: []int{0, 1}, Sum: 4},
+			[]uint64{digits(1), digits(3)},
+			[]uint64{digits(1), digits(3)}},
+
+		// 9^4 ways to fill these cages are beyond exactCageFills, so only
+		// the sum bounds are checked
+		{"cage bound exact", types.SumCage{Group: []int{0, 1, 2, 3}, Sum: 30},
+			[]uint64{span(1, 9), span(1, 9), span(1, 9), span(1, 9)},
+			[]uint64{span(6, 9), span(6, 9), span(6, 9), span(6, 9)}},
+		{"cage bound loose", types.SumCage{Group: []int{0, 1, 2, 3}, Sum: 11},
+			[]uint64{span(1, 9), span(1, 9), span(1, 9), span(1, 9)},
+			// 4 fits the bounds but takes part in no way to reach 11
+			[]uint64{span(1, 5), span(1, 5), span(1, 5), span(1, 5)}},
+		{"cage bound impossible", types.SumCage{Group: []int{0, 1, 2, 3}, Sum: 31},
+			[]uint64{span(1, 9), span(1, 9), span(1, 9), span(1, 9)}, nil},
+	} {
+		cands := slices.Clone(tc.cands)
+		ok := tc.rule.Restrict(cands)
+		switch {
+		case tc.want == nil && ok:
+			t.Errorf("%s: Restrict left %b, want failure", tc.name, cands)
+		case tc.want != nil && !ok:
+			t.Errorf("%s: Restrict failed, want %b", tc.name, tc.want)
+		case tc.want != nil && !slices.Equal(cands, tc.want):
+			t.Errorf("%s: Restrict left %b, want %b", tc.name, cands, tc.want)
+		}
+	}
+}
+
+// ruleCase pairs a rule with a plain statement of it to check against
+type ruleCase struct {
+	name  string
+	rule  types.Constraint
+	holds func(values []int) bool
+	exact bool // Restrict fails whenever no assignment keeps the rule
+}
+
+func ruleCases() []ruleCase {
+	distinct := func(values []int) bool {
+		seen := map[int]bool{}
+		for _, num := range values {
+			if seen[num] {
+				return false
+			}
+			seen[num] = true
+		}
+		return true
+	}
+	sum := func(values []int) int {
+		total := 0
+		for _, num := range values {
+			total += num
+		}
+		return total
+	}
+	abs := func(x int) int { return max(x, -x) }
+
+	return []ruleCase{
+		{"all different", types.AllDifferent{0, 1, 2}, distinct, false},
+		{"less", types.Relation{A: 0, B: 1, Kind: types.Less},
+			func(v []int) bool { return v[0] < v[1] }, true},
+		{"consecutive", types.Relation{A: 0, B: 1, Kind: types.Consecutive},
+			func(v []int) bool { return abs(v[0]-v[1]) == 1 }, true},
+		{"double", types.Relation{A: 0, B: 1, Kind: types.Double},
+			func(v []int) bool { return v[0] == 2*v[1] || v[1] == 2*v[0] }, true},
+		{"different", types.Relation{A: 0, B: 1, Kind: types.Different},
+			func(v []int) bool { return v[0] != v[1] }, true},
+		{"order line", types.OrderLine{0, 1, 2},
+			func(v []int) bool { return v[0] < v[1] && v[1] < v[2] }, true},
+		{"cage", types.SumCage{Group: []int{0, 1, 2}, Sum: 12},
+			func(v []int) bool { return distinct(v) && sum(v) == 12 }, true},
+		// Mostly too many ways to fill for the exact walk
+		{"big cage", types.SumCage{Group: []int{0, 1, 2, 3, 4}, Sum: 21},
+			func(v []int) bool { return distinct(v) && sum(v) == 21 }, false},
+	}
+}
+
+// assignments calls visit with every way to fill n cells with digits up
+// to max
+func assignments(n, max int, visit func(values []int)) {
+	values := make([]int, n)
+	var fill func(i int)
+	fill = func(i int) {
+		if i == n {
+			visit(values)
+			return
+		}
+		for num := 1; num <= max; num++ {
+			values[i] = num
+			fill(i + 1)
+		}
+	}
+	fill(0)
+}
+
+func TestSatisfiesRejectsBrokenRules(t *testing.T) {
+	for _, tc := range ruleCases() {
+		assignments(len(tc.rule.Cells()), 9, func(values []int) {
+			if got, want := types.Satisfies(tc.rule, values), tc.holds(values); got != want {
+				t.Fatalf("%s: Satisfies(%v) = %v, want %v", tc.name, values, got, want)
+			}
+		})
+	}
+}
+
+func TestRestrictKeepsEverySolution(t *testing.T) {
+	// Restrict may leave digits it cannot rule out, but must keep every
+	// digit of every assignment within the candidates that keeps the rule
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range ruleCases() {
+		n := len(tc.rule.Cells())
+		for i := 0; i < 200; i++ {
+			cands := make([]uint64, n)
+			for j := range cands {
+				cands[j] = (rng.Uint64() | rng.Uint64()) & span(1, 9)
+			}
+			need := make([]uint64, n)
+			assignments(n, 9, func(values []int) {
+				for j, num := range values {
+					if cands[j]&(1<<num) == 0 {
+						return
+					}
+				}
+				if tc.holds(values) {
+					for j, num := range values {
+						need[j] |= 1 << num
+					}
+				}
+			})
+
+			got := slices.Clone(cands)
+			ok := tc.rule.Restrict(got)
+			if need[0] == 0 {
+				if ok && tc.exact {
+					t.Fatalf("%s: Restrict(%b) kept %b, but nothing keeps the rule", tc.name, cands, got)
+				}
+				continue
+			}
+			if !ok {
+				t.Fatalf("%s: Restrict(%b) failed, but %b keeps the rule", tc.name, cands, need)
+			}
+			for j := range got {
+				if need[j]&^got[j] != 0 {
+					t.Fatalf("%s: Restrict(%b) left %b, dropping digits of %b", tc.name, cands, got, need)
+				}
+				if got[j]&^cands[j] != 0 {
+					t.Fatalf("%s: Restrict(%b) added digits: %b", tc.name, cands, got)
+				}
+			}
+		}
+	}
+}
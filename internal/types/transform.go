@@ -0,0 +1,382 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+var (
+	// ErrBreaksRegions is returned when a row or column swap would split a
+	// jigsaw region into disconnected pieces
+	ErrBreaksRegions = errors.New("transformation splits a jigsaw region")
+	// ErrBreaksVariant is returned when a transformation does not keep a
+	// variant's rules, such as a row swap on the diagonals of X, a relabeling
+	// of killer cage sums or a swap that splits a cage
+	ErrBreaksVariant = errors.New("transformation breaks the variant's rules")
+)
+
+// The transformations below return a new grid and leave g untouched. They
+// move the puzzle, the solution and the regions together, so a valid,
+// uniquely solvable puzzle stays one and needs the same techniques. The
+// result has no seed, since the generator cannot reproduce it. The
+// diagonals of X and the windows of hyper grids stay in place, so those
+// variants only allow rotations, reflections and relabeling. Killer cages
+// move with their cells but fix the digits their sums are made of, so
+// killer grids cannot be relabeled.
+
+// Clone returns a deep copy of g
+func (g *Grid) Clone() *Grid {
+	out := *g
+	out.Puzzle = cloneRows(g.Puzzle)
+	out.Solution = cloneRows(g.Solution)
+	out.SubGrids = cloneRows(g.SubGrids)
+	out.Variants = slices.Clone(g.Variants)
+	out.Cages = cloneCages(g.Cages)
+	return &out
+}
+
+// RelabelDigits replaces every digit d with perm[d-1]; perm must be a
+// permutation of 1..Size and every cell must be empty or hold 1..Size
+func (g *Grid) RelabelDigits(perm []int) (*Grid, error) {
+	if len(perm) != g.Size {
+		return nil, fmt.Errorf("digit permutation has %d entries, want %d", len(perm), g.Size)
+	}
+	if g.HasVariant(Killer) || len(g.Cages) > 0 {
+		return nil, ErrBreaksVariant
+	}
+	seen := make([]bool, g.Size+1)
+	for _, d := range perm {
+		if d < 1 || d > g.Size || seen[d] {
+			return nil, fmt.Errorf("invalid digit permutation %v", perm)
+		}
+		seen[d] = true
+	}
+
+	out := g.Clone()
+	out.Seed = 0
+	for _, rows := range [][][]int{out.Puzzle, out.Solution} {
+		for _, row := range rows {
+			for j, num := range row {
+				if num < 0 || num > g.Size {
+					return nil, fmt.Errorf("cell holds digit %d, outside 1 to %d", num, g.Size)
+				}
+				if num != 0 {
+					row[j] = perm[num-1]
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// SwapRows exchanges two rows. With boxes they must lie in the same band;
+// with jigsaw regions every region must stay connected.
+func (g *Grid) SwapRows(a, b int) (*Grid, error) {
+	if err := g.checkLines("row", "band", a, b, g.BoxHeight); err != nil {
+		return nil, err
+	}
+	return g.permute(swapped(g.Size, a, b), identity(g.Size))
+}
+
+// SwapColumns exchanges two columns. With boxes they must lie in the same
+// stack; with jigsaw regions every region must stay connected.
+func (g *Grid) SwapColumns(a, b int) (*Grid, error) {
+	if err := g.checkLines("column", "stack", a, b, g.BoxWidth); err != nil {
+		return nil, err
+	}
+	return g.permute(identity(g.Size), swapped(g.Size, a, b))
+}
+
+// SwapBands exchanges two bands, the rows of two rows of boxes
+func (g *Grid) SwapBands(a, b int) (*Grid, error) {
+	rows, err := g.swapGroups("band", a, b, g.BoxHeight)
+	if err != nil {
+		return nil, err
+	}
+	return g.permute(rows, identity(g.Size))
+}
+
+// SwapStacks exchanges two stacks, the columns of two columns of boxes
+func (g *Grid) SwapStacks(a, b int) (*Grid, error) {
+	cols, err := g.swapGroups("stack", a, b, g.BoxWidth)
+	if err != nil {
+		return nil, err
+	}
+	return g.permute(identity(g.Size), cols)
+}
+
+// Transpose mirrors the grid along its main diagonal. Rectangular boxes
+// turn on their side, so 3x4 boxes become 4x3 boxes.
+func (g *Grid) Transpose() *Grid {
+	return g.remap(func(r, c int) (int, int) { return c, r }, true)
+}
+
+// FlipHorizontal mirrors the grid left to right
+func (g *Grid) FlipHorizontal() *Grid {
+	return g.remap(func(r, c int) (int, int) { return r, g.Size - 1 - c }, false)
+}
+
+// FlipVertical mirrors the grid top to bottom
+func (g *Grid) FlipVertical() *Grid {
+	return g.remap(func(r, c int) (int, int) { return g.Size - 1 - r, c }, false)
+}
+
+// Rotate turns the grid clockwise by the given number of quarter turns;
+// negative turns go counterclockwise
+func (g *Grid) Rotate(turns int) *Grid {
+	switch (turns%4 + 4) % 4 {
+	case 1:
+		return g.remap(func(r, c int) (int, int) { return c, g.Size - 1 - r }, true)
+	case 2:
+		return g.remap(func(r, c int) (int, int) { return g.Size - 1 - r, g.Size - 1 - c }, false)
+	case 3:
+		return g.remap(func(r, c int) (int, int) { return g.Size - 1 - c, r }, true)
+	default:
+		out := g.Clone()
+		out.Seed = 0
+		return out
+	}
+}
+
+// RandomIsomorph applies a random combination of the transformations that
+// keep the layout: reflections always, digit relabeling unless the grid is
+// a killer, quarter turns unless the boxes are rectangular, and row,
+// column, band and stack swaps for box layouts without variants. It is a
+// cheap way to get many puzzles of the same difficulty from one.
+func (g *Grid) RandomIsomorph(rng *rand.Rand) *Grid {
+	out := g.Clone()
+	if g.Type != Jigsaw && len(g.Variants) == 0 && g.BoxWidth*g.BoxHeight == g.Size {
+		rows := groupedPerm(rng, g.Size, g.BoxHeight)
+		cols := groupedPerm(rng, g.Size, g.BoxWidth)
+		// Cages the swaps would split keep the lines in place
+		if permuted, err := out.permute(rows, cols); err == nil {
+			out = permuted
+		}
+	}
+	if g.Type == Jigsaw || g.BoxWidth == g.BoxHeight {
+		if rng.Intn(2) == 1 {
+			out = out.Transpose()
+		}
+		out = out.Rotate(rng.Intn(4))
+	} else {
+		out = out.Rotate(2 * rng.Intn(2))
+		if rng.Intn(2) == 1 {
+			out = out.FlipHorizontal()
+		}
+	}
+
+	if g.HasVariant(Killer) || len(g.Cages) > 0 {
+		return out
+	}
+	perm := rng.Perm(g.Size)
+	for i := range perm {
+		perm[i]++
+	}
+	if relabeled, err := out.RelabelDigits(perm); err == nil {
+		out = relabeled
+	}
+	return out
+}
+
+// checkLines validates two row or column indices for a swap; group is the
+// band height or stack width
+func (g *Grid) checkLines(kind, groupKind string, a, b, group int) error {
+	if a < 0 || a >= g.Size || b < 0 || b >= g.Size {
+		return fmt.Errorf("invalid %s %d or %d for a %dx%d grid", kind, a, b, g.Size, g.Size)
+	}
+	if g.Type != Jigsaw && group > 0 && a/group != b/group {
+		return fmt.Errorf("%ss %d and %d are in different %ss", kind, a, b, groupKind)
+	}
+	return nil
+}
+
+// swapGroups returns the line order that exchanges groups a and b of
+// width lines each
+func (g *Grid) swapGroups(kind string, a, b, width int) ([]int, error) {
+	if g.Type == Jigsaw {
+		return nil, fmt.Errorf("jigsaw grids have no %ss", kind)
+	}
+	if width < 1 || g.Size%width != 0 {
+		return nil, fmt.Errorf("invalid box size for a %dx%d grid", g.Size, g.Size)
+	}
+	groups := g.Size / width
+	if a < 0 || a >= groups || b < 0 || b >= groups {
+		return nil, fmt.Errorf("invalid %s %d or %d: grid has %d", kind, a, b, groups)
+	}
+	order := identity(g.Size)
+	for i := 0; i < width; i++ {
+		order[a*width+i], order[b*width+i] = b*width+i, a*width+i
+	}
+	return order, nil
+}
+
+// permute returns the grid whose row i is row rows[i] of g and whose
+// column j is column cols[j]
+func (g *Grid) permute(rows, cols []int) (*Grid, error) {
+	if g.HasVariant(X) || g.HasVariant(Hyper) {
+		return nil, ErrBreaksVariant
+	}
+	newRow, newCol := inverse(rows), inverse(cols)
+	out := g.remap(func(r, c int) (int, int) { return newRow[r], newCol[c] }, false)
+	if g.Type == Jigsaw {
+		for _, region := range out.SubGrids {
+			if !connected(region, g.Size) {
+				return nil, ErrBreaksRegions
+			}
+		}
+	}
+	for _, cage := range out.Cages {
+		if !connected(cage.Cells, g.Size) {
+			return nil, ErrBreaksVariant
+		}
+	}
+	return out, nil
+}
+
+// remap moves the cell at (r, c) to to(r, c). turned means rows become
+// columns, which swaps the box dimensions.
+func (g *Grid) remap(to func(r, c int) (int, int), turned bool) *Grid {
+	size := g.Size
+	out := &Grid{
+		Size:      size,
+		BoxWidth:  g.BoxWidth,
+		BoxHeight: g.BoxHeight,
+		Type:      g.Type,
+		Variants:  slices.Clone(g.Variants),
+	}
+	if turned {
+		out.BoxWidth, out.BoxHeight = g.BoxHeight, g.BoxWidth
+	}
+
+	move := func(rows [][]int) [][]int {
+		if len(rows) != size {
+			return nil
+		}
+		moved := make([][]int, size)
+		for i := range moved {
+			moved[i] = make([]int, size)
+		}
+		for r, row := range rows {
+			for c, num := range row {
+				nr, nc := to(r, c)
+				moved[nr][nc] = num
+			}
+		}
+		return moved
+	}
+	out.Puzzle = move(g.Puzzle)
+	out.Solution = move(g.Solution)
+
+	if g.Type == Jigsaw {
+		out.SubGrids = make([][]int, len(g.SubGrids))
+		for i, region := range g.SubGrids {
+			out.SubGrids[i] = make([]int, len(region))
+			for j, cell := range region {
+				nr, nc := to(cell/size, cell%size)
+				out.SubGrids[i][j] = nr*size + nc
+			}
+		}
+	} else if len(g.SubGrids) > 0 {
+		// Boxes map onto boxes, so keep the usual numbering
+		out.SubGrids = BoxRegions(size, out.BoxWidth, out.BoxHeight)
+	}
+
+	if g.Cages != nil {
+		out.Cages = make([]Cage, len(g.Cages))
+		for i, cage := range g.Cages {
+			cells := make([]int, len(cage.Cells))
+			for j, cell := range cage.Cells {
+				nr, nc := to(cell/size, cell%size)
+				cells[j] = nr*size + nc
+			}
+			out.Cages[i] = Cage{Cells: cells, Sum: cage.Sum}
+		}
+	}
+	return out
+}
+
+// connected reports whether the cells of region form one orthogonally
+// connected piece
+func connected(region []int, size int) bool {
+	if len(region) == 0 {
+		return true
+	}
+	in := make(map[int]bool, len(region))
+	for _, cell := range region {
+		in[cell] = true
+	}
+	seen := map[int]bool{region[0]: true}
+	stack := []int{region[0]}
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		r, c := cell/size, cell%size
+		for _, next := range []int{cell - size, cell + size, cell - 1, cell + 1} {
+			nr, nc := next/size, next%size
+			if next < 0 || !in[next] || seen[next] || (nr != r && nc != c) {
+				continue
+			}
+			seen[next] = true
+			stack = append(stack, next)
+		}
+	}
+	return len(seen) == len(in)
+}
+
+// groupedPerm returns a random line order that only moves whole groups of
+// width lines and lines within their group
+func groupedPerm(rng *rand.Rand, size, width int) []int {
+	order := make([]int, 0, size)
+	for _, group := range rng.Perm(size / width) {
+		for _, i := range rng.Perm(width) {
+			order = append(order, group*width+i)
+		}
+	}
+	return order
+}
+
+func identity(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func swapped(n, a, b int) []int {
+	order := identity(n)
+	order[a], order[b] = b, a
+	return order
+}
+
+func inverse(order []int) []int {
+	inv := make([]int, len(order))
+	for i, j := range order {
+		inv[j] = i
+	}
+	return inv
+}
+
+func cloneCages(cages []Cage) []Cage {
+	if cages == nil {
+		return nil
+	}
+	out := make([]Cage, len(cages))
+	for i, cage := range cages {
+		out[i] = Cage{Cells: slices.Clone(cage.Cells), Sum: cage.Sum}
+	}
+	return out
+}
+
+func cloneRows(rows [][]int) [][]int {
+	if rows == nil {
+		return nil
+	}
+	out := make([][]int, len(rows))
+	for i, row := range rows {
+		out[i] = append([]int(nil), row...)
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+package types_test
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"sudoku_gen_go/internal/solver"
+	"sudoku_gen_go/internal/types"
+	"testing"
+)
+
+// classic is a 9x9 puzzle with a unique solution
+var classic = &types.Grid{
+	Size:      9,
+	BoxWidth:  3,
+	BoxHeight: 3,
+	Type:      types.Normal,
+	Puzzle: [][]int{
+		{5, 3, 0, 0, 7, 0, 0, 0, 0},
+		{6, 0, 0, 1, 9, 5, 0, 0, 0},
+		{0, 9, 8, 0, 0, 0, 0, 6, 0},
+		{8, 0, 0, 0, 6, 0, 0, 0, 3},
+		{4, 0, 0, 8, 0, 3, 0, 0, 1},
+		{7, 0, 0, 0, 2, 0, 0, 0, 6},
+		{0, 6, 0, 0, 0, 0, 2, 8, 0},
+		{0, 0, 0, 4, 1, 9, 0, 0, 5},
+		{0, 0, 0, 0, 8, 0, 0, 7, 9},
+	},
+	Solution: [][]int{
+		{5, 3, 4, 6, 7, 8, 9, 1, 2},
+		{6, 7, 2, 1, 9, 5, 3, 4, 8},
+		{1, 9, 8, 3, 4, 2, 5, 6, 7},
+		{8, 5, 9, 7, 6, 1, 4, 2, 3},
+		{4, 2, 6, 8, 5, 3, 7, 9, 1},
+		{7, 1, 3, 9, 2, 4, 8, 5, 6},
+		{9, 6, 1, 5, 3, 7, 2, 8, 4},
+		{2, 8, 7, 4, 1, 9, 6, 3, 5},
+		{3, 4, 5, 2, 8, 6, 1, 7, 9},
+	},
+	SubGrids: types.BoxRegions(9, 3, 3),
+}
+
+// fixtures are puzzles of every layout and variant the transforms treat
+// differently, written row by row with 0 for empty cells. Regions default
+// to boxes.
+var fixtures = []struct {
+	name     string
+	size     int
+	typ      types.SudokuType
+	variants []types.SudokuType
+	puzzle   string
+	solution string
+	regions  [][]int
+	cages    []types.Cage
+}{
+	{name: "6x6", size: 6, typ: types.Normal,
+		puzzle:   "045021201500053000402000000000004236",
+		solution: "645321231564153642462153326415514236"},
+	{name: "jigsaw", size: 6, typ: types.Jigsaw,
+		puzzle:   "000031000250501062450003003120000040",
+		solution: "264531316254531462452613643125125346",
+		regions: [][]int{
+			{20, 21, 27, 15, 33, 19}, {6, 12, 7, 0, 1, 18}, {26, 32, 25, 24, 31, 30},
+			{14, 8, 9, 2, 13, 10}, {28, 34, 35, 29, 23, 22}, {5, 11, 17, 16, 4, 3},
+		}},
+	{name: "x", size: 9, typ: types.Normal, variants: []types.SudokuType{types.X},
+		puzzle:   "205400000000905821019702065001000006008050000004100570596800032002500900040000050",
+		solution: "285416793467935821319782465751349286928657314634128579596874132172563948843291657"},
+	{name: "hyper", size: 9, typ: types.Normal, variants: []types.SudokuType{types.Hyper},
+		puzzle:   "280000107060700020700100548000067900501084206002900000810000090000002730407003800",
+		solution: "284539167165748329739126548348267915591384276672951483813475692956812734427693851"},
+	{name: "killer", size: 6, typ: types.Normal, variants: []types.SudokuType{types.Killer},
+		puzzle:   "000000000000000000000000006000000000",
+		solution: "645321231564153642462153326415514236",
+		cages: []types.Cage{
+			{Cells: []int{5, 11, 4}, Sum: 7}, {Cells: []int{10, 9}, Sum: 11}, {Cells: []int{3, 2, 8}, Sum: 9},
+			{Cells: []int{1, 0, 6}, Sum: 12}, {Cells: []int{7, 13}, Sum: 8}, {Cells: []int{12, 18}, Sum: 5},
+			{Cells: []int{30, 24}, Sum: 8}, {Cells: []int{17, 16}, Sum: 6}, {Cells: []int{35, 34, 28}, Sum: 10},
+			{Cells: []int{29, 23}, Sum: 8}, {Cells: []int{22, 21, 27}, Sum: 10}, {Cells: []int{33, 32}, Sum: 6},
+			{Cells: []int{15, 14}, Sum: 9}, {Cells: []int{31, 25, 26}, Sum: 9}, {Cells: []int{19, 20}, Sum: 8},
+		}},
+}
+
+// testGrids returns the classic puzzle and every fixture, each built
+// afresh so tests cannot change each other's grids
+func testGrids(t *testing.T) map[string]*types.Grid {
+	t.Helper()
+	grids := map[string]*types.Grid{"classic": classic.Clone()}
+	for _, f := range fixtures {
+		grid := types.NewGrid(f.size, f.typ)
+		grid.Variants = f.variants
+		grid.Cages = f.cages
+		grid.SubGrids = f.regions
+		if grid.SubGrids == nil {
+			grid.SubGrids = types.BoxRegions(f.size, grid.BoxWidth, grid.BoxHeight)
+		}
+		if len(f.puzzle) != f.size*f.size || len(f.solution) != f.size*f.size {
+			t.Fatalf("%s: fixture does not fill a %dx%d grid", f.name, f.size, f.size)
+		}
+		for i := range f.puzzle {
+			grid.Puzzle[i/f.size][i%f.size] = int(f.puzzle[i] - '0')
+			grid.Solution[i/f.size][i%f.size] = int(f.solution[i] - '0')
+		}
+		grids[f.name] = grid
+	}
+	return grids
+}
+
+// checkPuzzle fails unless grid is uniquely solvable with its stored
+// solution and that solution keeps every rule
+func checkPuzzle(t *testing.T, name string, grid *types.Grid) {
+	t.Helper()
+	if n := solver.CountSolutions(grid, 2); n != 1 {
+		t.Fatalf("%s: %d solutions, want 1", name, n)
+	}
+	solution, err := solver.Solve(grid)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if !reflect.DeepEqual(solution, grid.Solution) {
+		t.Fatalf("%s: solved to %v, stored solution is %v", name, solution, grid.Solution)
+	}
+	if err := grid.CheckVariants(); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if err := grid.CheckConstraints(grid.Solution); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+func TestTransformsKeepPuzzlesValid(t *testing.T) {
+	transforms := []struct {
+		name string
+		fn   func(*types.Grid) (*types.Grid, error)
+	}{
+		{"relabel", func(g *types.Grid) (*types.Grid, error) {
+			perm := make([]int, g.Size)
+			for i := range perm {
+				perm[i] = g.Size - i
+			}
+			return g.RelabelDigits(perm)
+		}},
+		{"swap rows", func(g *types.Grid) (*types.Grid, error) { return g.SwapRows(0, 1) }},
+		{"swap columns", func(g *types.Grid) (*types.Grid, error) { return g.SwapColumns(1, 2) }},
+		{"swap bands", func(g *types.Grid) (*types.Grid, error) { return g.SwapBands(0, 1) }},
+		{"swap stacks", func(g *types.Grid) (*types.Grid, error) { return g.SwapStacks(0, 1) }},
+		{"transpose", func(g *types.Grid) (*types.Grid, error) { return g.Transpose(), nil }},
+		{"flip horizontal", func(g *types.Grid) (*types.Grid, error) { return g.FlipHorizontal(), nil }},
+		{"flip vertical", func(g *types.Grid) (*types.Grid, error) { return g.FlipVertical(), nil }},
+		{"rotate 1", func(g *types.Grid) (*types.Grid, error) { return g.Rotate(1), nil }},
+		{"rotate 2", func(g *types.Grid) (*types.Grid, error) { return g.Rotate(2), nil }},
+		{"rotate 3", func(g *types.Grid) (*types.Grid, error) { return g.Rotate(3), nil }},
+	}
+
+	for name, grid := range testGrids(t) {
+		before := grid.Clone()
+		for _, tr := range transforms {
+			out, err := tr.fn(grid)
+			if err != nil {
+				// Refusing is fine as long as it is for the layout's sake
+				if !errors.Is(err, types.ErrBreaksVariant) && !errors.Is(err, types.ErrBreaksRegions) && grid.Type != types.Jigsaw {
+					t.Fatalf("%s, %s: %v", name, tr.name, err)
+				}
+				continue
+			}
+			checkPuzzle(t, name+", "+tr.name, out)
+		}
+		if !reflect.DeepEqual(grid, before) {
+			t.Fatalf("%s: transforms changed the original grid", name)
+		}
+	}
+}
+
+func TestRelabelDigitsRejectsBadDigits(t *testing.T) {
+	for _, num := range []int{7, -1} {
+		grid := types.NewGrid(4, types.Normal)
+		grid.Puzzle[1][2] = num
+		if _, err := grid.RelabelDigits([]int{2, 1, 4, 3}); err == nil {
+			t.Errorf("relabeled a grid holding %d", num)
+		}
+
+		grid = types.NewGrid(4, types.Normal)
+		grid.Solution = [][]int{{1, 2, 3, 4}, {3, 4, 1, 2}, {2, 1, 4, 3}, {4, 3, 2, num}}
+		if _, err := grid.RelabelDigits([]int{2, 1, 4, 3}); err == nil {
+			t.Errorf("relabeled a solution holding %d", num)
+		}
+	}
+}
+
+func TestRandomIsomorphKeepsPuzzlesValid(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, grid := range testGrids(t) {
+		for i := 0; i < 10; i++ {
+			checkPuzzle(t, name, grid.RandomIsomorph(rng))
+		}
+	}
+}
+
+func TestRandomIsomorphStrayCages(t *testing.T) {
+	// Cages without the killer variant are bad input, but must not crash
+	grid := classic.Clone()
+	grid.Cages = []types.Cage{{Cells: []int{0, 1}, Sum: 8}}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		out := grid.RandomIsomorph(rng)
+		if out == nil || len(out.Cages) != 1 {
+			t.Fatal("RandomIsomorph lost the grid or its cages")
+		}
+	}
+}
@@ -1,12 +1,23 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+)
 
+// SudokuType is either a layout, Normal for boxes or Jigsaw for irregular
+// regions, or a variant that adds rules on top of a layout
 type SudokuType string
 
 const (
 	Normal SudokuType = "normal"
 	Jigsaw SudokuType = "jigsaw"
+	// X also keeps each digit once on both main diagonals
+	X SudokuType = "x"
+	// Hyper also keeps each digit once in shaded windows, see HyperWindows
+	Hyper SudokuType = "hyper"
+	// Killer splits the grid into cages whose digits add up to a given sum
+	Killer SudokuType = "killer"
 )
 
 // Grid represents a flexible Sudoku grid
@@ -18,10 +29,31 @@ type Grid struct {
 	Solution  [][]int    `json:"solution"`
 	SubGrids  [][]int    `json:"regions"`    // Renamed from SubGrids to match JS
 	Type      SudokuType `json:"layoutType"` // Renamed from Type to match JS
+	// Variants lists the variants whose rules apply on top of the layout
+	Variants []SudokuType `json:"variants,omitempty"`
+	// Cages are the cages of a killer grid
+	Cages []Cage `json:"cages,omitempty"`
+	Seed  int64  `json:"seed"` // Generator seed that reproduces this puzzle
+}
+
+// Cage is a killer cage: cells, as row*Size+col indices, whose digits do
+// not repeat and add up to Sum
+type Cage struct {
+	Cells []int `json:"cells"`
+	Sum   int   `json:"sum"`
 }
 
-// NewGrid creates a new Grid instance
+// NewGrid creates a new Grid instance with the default boxes for its size.
+// Sizes without a box shape, such as primes, get no box dimensions and
+// need jigsaw regions.
 func NewGrid(size int, typ SudokuType) *Grid {
+	boxWidth, boxHeight, _ := BoxDimensions(size)
+	return NewGridWithBoxes(size, boxWidth, boxHeight, typ)
+}
+
+// NewGridWithBoxes creates a new Grid instance with boxWidth x boxHeight
+// boxes
+func NewGridWithBoxes(size, boxWidth, boxHeight int, typ SudokuType) *Grid {
 	puzzle := make([][]int, size)
 	solution := make([][]int, size)
 	for i := range puzzle {
@@ -29,8 +61,6 @@ func NewGrid(size int, typ SudokuType) *Grid {
 		solution[i] = make([]int, size)
 	}
 
-	boxWidth, boxHeight := getBoxDimensions(size)
-
 	return &Grid{
 		Size:      size,
 		BoxWidth:  boxWidth,
@@ -41,17 +71,75 @@ func NewGrid(size int, typ SudokuType) *Grid {
 	}
 }
 
-func getBoxDimensions(size int) (width, height int) {
-	switch size {
-	case 9:
-		return 3, 3
-	case 12:
-		return 3, 4
-	case 16:
-		return 4, 4
-	default:
-		return 3, 3
+// BoxDimensions returns the default box shape for a grid size: the most
+// square boxes, wider than tall, such as 3x2 for 6 or 5x2 for 10. 12 keeps
+// the 3x4 boxes it has always used. Sizes that only split into rows, such
+// as primes, have no box shape.
+func BoxDimensions(size int) (width, height int, err error) {
+	if size == 12 {
+		return 3, 4, nil
+	}
+	for height = 1; (height+1)*(height+1) <= size; height++ {
+	}
+	for ; height > 1; height-- {
+		if size%height == 0 {
+			return size / height, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no box shape for a %dx%d grid", size, size)
+}
+
+// CheckBoxes reports whether boxWidth x boxHeight boxes tile a grid of
+// the given size. Boxes one cell wide or tall would repeat a row or column
+// and are rejected.
+func CheckBoxes(size, boxWidth, boxHeight int) error {
+	if boxWidth < 2 || boxHeight < 2 || boxWidth*boxHeight != size {
+		return fmt.Errorf("%dx%d boxes do not tile a %dx%d grid", boxWidth, boxHeight, size, size)
+	}
+	return nil
+}
+
+// CheckRegions reports whether regions split a grid of the given size
+// into size regions of size cells each, covering every cell once
+func CheckRegions(size int, regions [][]int) error {
+	if len(regions) != size {
+		return fmt.Errorf("%d regions, want %d", len(regions), size)
+	}
+	covered := make([]bool, size*size)
+	for r, region := range regions {
+		if len(region) != size {
+			return fmt.Errorf("region %d has %d cells, want %d", r, len(region), size)
+		}
+		for _, idx := range region {
+			if idx < 0 || idx >= size*size || covered[idx] {
+				return fmt.Errorf("region %d has invalid cell %d", r, idx)
+			}
+			covered[idx] = true
+		}
+	}
+	return nil
+}
+
+// BoxRegions returns the cell indices of every box in a grid made of
+// boxWidth x boxHeight boxes, numbered left to right, top to bottom
+func BoxRegions(size, boxWidth, boxHeight int) [][]int {
+	regions := make([][]int, 0, size)
+
+	for boxRow := 0; boxRow < size/boxHeight; boxRow++ {
+		for boxCol := 0; boxCol < size/boxWidth; boxCol++ {
+			region := make([]int, 0, size)
+			for i := 0; i < boxHeight; i++ {
+				for j := 0; j < boxWidth; j++ {
+					row := boxRow*boxHeight + i
+					col := boxCol*boxWidth + j
+					region = append(region, row*size+col)
+				}
+			}
+			regions = append(regions, region)
+		}
 	}
+
+	return regions
 }
 
 // ToJSON converts the grid to JSON bytes
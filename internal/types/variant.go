@@ -0,0 +1,185 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// VariantTypes lists every variant a grid can combine with its layout
+var VariantTypes = []SudokuType{X, Hyper, Killer}
+
+// IsVariant reports whether t is a variant rather than a layout
+func (t SudokuType) IsVariant() bool {
+	return slices.Contains(VariantTypes, t)
+}
+
+// ParseVariants reads a comma-separated list of variants such as "x". An
+// empty list means none.
+func ParseVariants(list string) ([]SudokuType, error) {
+	var variants []SudokuType
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		t := SudokuType(name)
+		if !t.IsVariant() {
+			names := make([]string, len(VariantTypes))
+			for i, v := range VariantTypes {
+				names[i] = string(v)
+			}
+			return nil, fmt.Errorf("unknown variant %q: must be one of %s", name, strings.Join(names, ", "))
+		}
+		if !slices.Contains(variants, t) {
+			variants = append(variants, t)
+		}
+	}
+	return variants, nil
+}
+
+// CheckVariants reports unknown variants and variants the grid's shape
+// cannot hold, such as hyper windows that do not fit its boxes. Killer
+// cages must cover every cell once; a killer grid without cages is still
+// being generated and passes.
+func (g *Grid) CheckVariants() error {
+	for _, v := range g.Variants {
+		if !v.IsVariant() {
+			return fmt.Errorf("unknown variant %q", v)
+		}
+		if v == Hyper {
+			if _, err := HyperWindows(g.Size, g.BoxWidth, g.BoxHeight); err != nil {
+				return err
+			}
+		}
+	}
+	if len(g.Cages) == 0 {
+		return nil
+	}
+	if !g.HasVariant(Killer) {
+		return fmt.Errorf("cages need the %s variant", Killer)
+	}
+	return checkCages(g.Cages, g.Size)
+}
+
+// checkCages reports cages that leave a cell uncovered, cover it twice or
+// could never reach their sum
+func checkCages(cages []Cage, size int) error {
+	covered := make([]bool, size*size)
+	for i, cage := range cages {
+		if len(cage.Cells) == 0 || len(cage.Cells) > size {
+			return fmt.Errorf("cage %d has %d cells, want 1 to %d", i, len(cage.Cells), size)
+		}
+		for _, cell := range cage.Cells {
+			if cell < 0 || cell >= size*size || covered[cell] {
+				return fmt.Errorf("cage %d has invalid cell %d", i, cell)
+			}
+			covered[cell] = true
+		}
+		// The cage's digits are distinct, so its sum lies between the
+		// sums of the smallest and of the largest digits
+		n := len(cage.Cells)
+		if lo, hi := n*(n+1)/2, n*(2*size-n+1)/2; cage.Sum < lo || cage.Sum > hi {
+			return fmt.Errorf("cage %d of %d cells cannot sum to %d", i, n, cage.Sum)
+		}
+	}
+	for cell, ok := range covered {
+		if !ok {
+			return fmt.Errorf("cell %d is in no cage", cell)
+		}
+	}
+	return nil
+}
+
+// HasVariant reports whether the rules of variant t apply to the grid
+func (g *Grid) HasVariant(t SudokuType) bool {
+	return slices.Contains(g.Variants, t)
+}
+
+// Diagonals returns the cells of the main diagonal, top left to bottom
+// right, and of the anti-diagonal, top right to bottom left
+func Diagonals(size int) [][]int {
+	main := make([]int, size)
+	anti := make([]int, size)
+	for i := 0; i < size; i++ {
+		main[i] = i*size + i
+		anti[i] = i*size + size - 1 - i
+	}
+	return [][]int{main, anti}
+}
+
+// HyperWindows returns the shaded windows of a hyper grid, numbered left to
+// right, top to bottom. Windows have the shape of the grid's boxes and are
+// set off from the edges and from each other by gaps, laid out the same way
+// from either side so that rotations and reflections keep them in place.
+// 9x9 grids get the classic four windows at rows and columns 2-4 and 6-8,
+// 16x16 grids nine and 25x25 grids sixteen. Some shapes, such as the 3x2
+// boxes of 6x6 grids, leave no symmetric layout.
+func HyperWindows(size, boxWidth, boxHeight int) ([][]int, error) {
+	if err := CheckBoxes(size, boxWidth, boxHeight); err != nil {
+		return nil, fmt.Errorf("hyper windows need boxes: %v", err)
+	}
+	rows := windowStarts(size, boxHeight)
+	cols := windowStarts(size, boxWidth)
+	if rows == nil || cols == nil {
+		return nil, fmt.Errorf("no hyper window layout for %dx%d boxes in a %dx%d grid", boxWidth, boxHeight, size, size)
+	}
+
+	windows := make([][]int, 0, len(rows)*len(cols))
+	for _, top := range rows {
+		for _, left := range cols {
+			window := make([]int, 0, size)
+			for r := top; r < top+boxHeight; r++ {
+				for c := left; c < left+boxWidth; c++ {
+					window = append(window, r*size+c)
+				}
+			}
+			windows = append(windows, window)
+		}
+	}
+	return windows, nil
+}
+
+// windowStarts places as many windows of the given length on a line of
+// size cells as fit with a gap before, between and after them. The two
+// outer gaps are equal, the inner gaps are equal, and of the layouts that
+// allows it picks the one where they differ least. It returns nil when
+// the gaps cannot be made symmetric.
+func windowStarts(size, length int) []int {
+	for count := (size - 1) / (length + 1); count >= 1; count-- {
+		gaps := size - count*length
+		outer, inner := 0, 0
+		if count == 1 {
+			if gaps%2 == 0 {
+				outer = gaps / 2
+			}
+		} else {
+			for in := 1; gaps-(count-1)*in >= 2; in++ {
+				rest := gaps - (count-1)*in
+				if rest%2 != 0 {
+					continue
+				}
+				if out := rest / 2; outer == 0 || abs(out-in) < abs(outer-inner) {
+					outer, inner = out, in
+				}
+			}
+		}
+		if outer == 0 {
+			continue
+		}
+
+		starts := make([]int, count)
+		for i := range starts {
+			starts[i] = outer + i*(length+inner)
+		}
+		return starts
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,167 @@
+package visualizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edge is how a cell border is drawn in a cage outline
+type edge int
+
+const (
+	noEdge     edge = iota
+	regionEdge      // region or box boundary inside a cage
+	cageEdge
+)
+
+// outline holds the characters cage outlines are drawn with
+type outline struct {
+	// horizontal and vertical draw region and cage edges, by edge
+	horizontal, vertical [3]string
+	// corners draws a corner touched by cage edges, indexed by the sides
+	// they leave on: 1 up, 2 down, 4 left and 8 right. regionCorner is
+	// used when only region edges touch it.
+	corners      [16]string
+	regionCorner string
+	styled       bool
+}
+
+var (
+	boxOutline = outline{
+		horizontal:   [3]string{" ", "┄", "─"},
+		vertical:     [3]string{" ", "┆", "│"},
+		corners:      [16]string{" ", "╵", "╷", "│", "╴", "┘", "┐", "┤", "╶", "└", "┌", "├", "─", "┴", "┬", "┼"},
+		regionCorner: "·",
+		styled:       true,
+	}
+	asciiOutline = outline{
+		horizontal:   [3]string{" ", ".", "-"},
+		vertical:     [3]string{" ", ":", "|"},
+		corners:      [16]string{" ", "+", "+", "|", "+", "+", "+", "+", "+", "+", "+", "+", "-", "+", "+", "+"},
+		regionCorner: ".",
+	}
+)
+
+// PrintCages prints a killer grid with box-drawn cage outlines and each
+// cage's sum in its first cell. Region and box boundaries inside a cage
+// are dashed.
+func (v *Visualizer) PrintCages() {
+	v.printCages(boxOutline)
+}
+
+// PrintCagesText prints the cage outlines of PrintCages with plain ASCII
+// characters and no colours, marking cells like PrintText
+func (v *Visualizer) PrintCagesText() {
+	v.printCages(asciiOutline)
+}
+
+func (v *Visualizer) printCages(o outline) {
+	size := v.grid.Size
+	regionOf := make([]int, size*size)
+	for cell := range regionOf {
+		regionOf[cell] = v.findRegionIndex(cell)
+	}
+
+	// Sums go in the first cell of their cage, in row order
+	sums := make(map[int]int, len(v.grid.Cages))
+	width := len(fmt.Sprint(size))
+	if !o.styled {
+		// Text output marks diagonals and windows after the digit
+		width = len(v.textCell(0, 0))
+	}
+	for _, cage := range v.grid.Cages {
+		first := cage.Cells[0]
+		for _, cell := range cage.Cells {
+			first = min(first, cell)
+		}
+		sums[first] = cage.Sum
+		width = max(width, len(fmt.Sprint(cage.Sum)))
+	}
+	width += 2
+
+	// between returns the edge between two cells, given as -1 outside the
+	// grid
+	between := func(a, b int) edge {
+		switch {
+		case a < 0 && b < 0:
+			return noEdge
+		case a < 0 || b < 0:
+			return cageEdge
+		case v.cageOf[a] != v.cageOf[b]:
+			return cageEdge
+		case regionOf[a] != regionOf[b]:
+			return regionEdge
+		}
+		return noEdge
+	}
+	cell := func(i, j int) int {
+		if i < 0 || i >= size || j < 0 || j >= size {
+			return -1
+		}
+		return i*size + j
+	}
+
+	var line strings.Builder
+	for i := 0; i <= size; i++ {
+		// Border above row i
+		line.Reset()
+		for j := 0; j <= size; j++ {
+			up := between(cell(i-1, j-1), cell(i-1, j))
+			down := between(cell(i, j-1), cell(i, j))
+			left := between(cell(i-1, j-1), cell(i, j-1))
+			right := between(cell(i-1, j), cell(i, j))
+			line.WriteString(o.corner(up, down, left, right))
+			if j < size {
+				line.WriteString(strings.Repeat(o.horizontal[right], width))
+			}
+		}
+		fmt.Println(line.String())
+		if i == size {
+			break
+		}
+
+		// The sum line, then the digit line
+		for _, digits := range []bool{false, true} {
+			line.Reset()
+			for j := 0; j <= size; j++ {
+				line.WriteString(o.vertical[between(cell(i, j-1), cell(i, j))])
+				if j == size {
+					break
+				}
+				if !digits {
+					text := ""
+					if sum, ok := sums[i*size+j]; ok {
+						text = fmt.Sprint(sum)
+					}
+					line.WriteString(fmt.Sprintf("%-*s", width, text))
+					continue
+				}
+				text := v.cellText(i, j)
+				if !o.styled {
+					text = v.textCell(i, j)
+				}
+				pad := (width - len(text)) / 2
+				text = strings.Repeat(" ", pad) + text + strings.Repeat(" ", width-pad-len(text))
+				if o.styled {
+					text = v.styled(i, j, text)
+				}
+				line.WriteString(text)
+			}
+			fmt.Println(line.String())
+		}
+	}
+}
+
+// corner returns the character where four cell borders meet
+func (o outline) corner(up, down, left, right edge) string {
+	index := 0
+	for bit, e := range []edge{up, down, left, right} {
+		if e == cageEdge {
+			index |= 1 << bit
+		}
+	}
+	if index == 0 && max(up, down, left, right) == regionEdge {
+		return o.regionCorner
+	}
+	return o.corners[index]
+}
@@ -2,65 +2,95 @@ package visualizer
 
 import (
 	"fmt"
-	"math"
 	"strings"
 	"sudoku_gen_go/internal/types"
 )
 
+const (
+	// underline marks the diagonal cells of X grids on terminals
+	underline = "\033[4m"
+	// shade marks the windows of hyper grids, and inverse does on jigsaw
+	// grids, whose regions already have background colours
+	shade   = "\033[100m"
+	inverse = "\033[7m"
+	reset   = "\033[0m"
+)
+
 // Visualizer handles grid visualization
 type Visualizer struct {
-	grid *types.Grid
+	grid   *types.Grid
+	shaded []bool // cells in the windows of a hyper grid
+	cageOf []int  // cage index of each cell of a killer grid
 }
 
 func NewVisualizer(grid *types.Grid) *Visualizer {
-	return &Visualizer{grid: grid}
+	v := &Visualizer{grid: grid}
+	if grid.HasVariant(types.Hyper) {
+		windows, _ := types.HyperWindows(grid.Size, grid.BoxWidth, grid.BoxHeight)
+		v.shaded = make([]bool, grid.Size*grid.Size)
+		for _, window := range windows {
+			for _, cell := range window {
+				v.shaded[cell] = true
+			}
+		}
+	}
+	if len(grid.Cages) > 0 {
+		v.cageOf = make([]int, grid.Size*grid.Size)
+		for i, cage := range grid.Cages {
+			for _, cell := range cage.Cells {
+				v.cageOf[cell] = i
+			}
+		}
+	}
+	return v
 }
 
 func (v *Visualizer) Print() {
 	size := v.grid.Size
 	maxDigits := len(fmt.Sprint(size))
+	boxWidth, boxHeight := v.boxDimensions()
 
 	// Print top border
-	v.printHorizontalBorder(size, maxDigits)
+	v.printHorizontalBorder(size, maxDigits, boxWidth)
 
 	// Print rows
 	for i := 0; i < size; i++ {
 		fmt.Print("│ ")
 		for j := 0; j < size; j++ {
-			if v.grid.Puzzle[i][j] == 0 {
-				fmt.Printf("%-*s", maxDigits, ".")
-			} else {
-				fmt.Printf("%-*d", maxDigits, v.grid.Puzzle[i][j])
-			}
-			fmt.Print(" ")
+			fmt.Print(v.styled(i, j, fmt.Sprintf("%-*s", maxDigits, v.cellText(i, j))) + " ")
 
 			// Print vertical borders
-			if (j+1)%v.getBoxSize() == 0 && j < size-1 {
+			if (j+1)%boxWidth == 0 && j < size-1 {
 				fmt.Print("│ ")
 			}
 		}
 		fmt.Println("│")
 
 		// Print horizontal borders
-		if (i+1)%v.getBoxSize() == 0 && i < size-1 {
-			v.printHorizontalBorder(size, maxDigits)
+		if (i+1)%boxHeight == 0 && i < size-1 {
+			v.printHorizontalBorder(size, maxDigits, boxWidth)
 		}
 	}
 
 	// Print bottom border
-	v.printHorizontalBorder(size, maxDigits)
+	v.printHorizontalBorder(size, maxDigits, boxWidth)
 }
 
-func (v *Visualizer) getBoxSize() int {
-	return int(math.Sqrt(float64(v.grid.Size)))
+// boxDimensions returns the grid's box shape, or whole rows and columns
+// when the grid has no valid boxes so no inner borders are drawn
+func (v *Visualizer) boxDimensions() (width, height int) {
+	g := v.grid
+	if types.CheckBoxes(g.Size, g.BoxWidth, g.BoxHeight) != nil {
+		return g.Size, g.Size
+	}
+	return g.BoxWidth, g.BoxHeight
 }
 
-func (v *Visualizer) printHorizontalBorder(size, maxDigits int) {
-	boxSize := v.getBoxSize()
+func (v *Visualizer) printHorizontalBorder(size, maxDigits, boxWidth int) {
 	fmt.Print("├")
 	for i := 0; i < size; i++ {
 		fmt.Print(strings.Repeat("─", maxDigits+1))
-		if (i+1)%boxSize == 0 && i < size-1 {
+		if (i+1)%boxWidth == 0 && i < size-1 {
 			fmt.Print("┼")
 		}
 	}
@@ -89,7 +119,6 @@ func (v *Visualizer) PrintJigsaw() {
 		"\033[106m", // Bright Cyan background
 		"\033[107m", // Bright White background
 	}
-	reset := "\033[0m"
 
 	// Print top border
 	borderWidth := size*(maxDigits+1) + 1
@@ -102,12 +131,14 @@ func (v *Visualizer) PrintJigsaw() {
 			cellIndex := i*size + j
 			regionIndex := v.findRegionIndex(cellIndex)
 			colorCode := colors[regionIndex%len(colors)]
-
-			if v.grid.Puzzle[i][j] == 0 {
-				fmt.Printf("%s%-*s%s", colorCode, maxDigits, ".", reset)
-			} else {
-				fmt.Printf("%s%-*d%s", colorCode, maxDigits, v.grid.Puzzle[i][j], reset)
+			if v.onDiagonal(i, j) {
+				colorCode += underline
 			}
+			if v.isShaded(i, j) {
+				colorCode += inverse
+			}
+
+			fmt.Printf("%s%-*s%s", colorCode, maxDigits, v.cellText(i, j), reset)
 			fmt.Print(" ")
 		}
 		fmt.Println("│")
@@ -127,3 +158,97 @@ func (v *Visualizer) findRegionIndex(cellIndex int) int {
 	}
 	return -1
 }
+
+// PrintText prints the puzzle as plain rows of digits with '.' for empty
+// cells, suitable for files and terminals without box drawing or colours.
+// Cells on the diagonals of X grids are followed by the diagonal, '\', '/'
+// or 'X' where they cross, and cells in the windows of hyper grids by '#'.
+func (v *Visualizer) PrintText() {
+	size := v.grid.Size
+
+	for i := 0; i < size; i++ {
+		cells := make([]string, size)
+		for j := 0; j < size; j++ {
+			cells[j] = v.textCell(i, j)
+		}
+		fmt.Println(strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+}
+
+// textCell returns the digit in a cell or '.', right-aligned, followed by
+// the cell's markers for text output
+func (v *Visualizer) textCell(i, j int) string {
+	num := "."
+	if n := v.grid.Puzzle[i][j]; n != 0 {
+		num = fmt.Sprint(n)
+	}
+	marks := ""
+	if v.onDiagonal(i, j) {
+		marks += v.diagonalMark(i, j)
+	}
+	if v.isShaded(i, j) {
+		marks += "#"
+	}
+	width := 0
+	if v.grid.HasVariant(types.X) {
+		width++
+	}
+	if v.shaded != nil {
+		width++
+	}
+	return fmt.Sprintf("%*s%-*s", len(fmt.Sprint(v.grid.Size)), num, width, marks)
+}
+
+// cellText returns the digit in a cell, or for an empty cell '.', on the
+// diagonals of X grids '\', '/' or 'X' where they cross, and in the
+// windows of hyper grids '#'
+func (v *Visualizer) cellText(i, j int) string {
+	if num := v.grid.Puzzle[i][j]; num != 0 {
+		return fmt.Sprint(num)
+	}
+	if !v.onDiagonal(i, j) {
+		if v.isShaded(i, j) {
+			return "#"
+		}
+		return "."
+	}
+	return v.diagonalMark(i, j)
+}
+
+// diagonalMark returns '\', '/' or 'X' for a cell on the diagonals
+func (v *Visualizer) diagonalMark(i, j int) string {
+	switch last := v.grid.Size - 1; {
+	case i == j && i+j == last:
+		return "X"
+	case i == j:
+		return "\\"
+	default:
+		return "/"
+	}
+}
+
+// styled marks the text of a cell on a diagonal of an X grid or in a
+// window of a hyper grid for terminals
+func (v *Visualizer) styled(i, j int, text string) string {
+	style := ""
+	if v.onDiagonal(i, j) {
+		style += underline
+	}
+	if v.isShaded(i, j) {
+		style += shade
+	}
+	if style == "" {
+		return text
+	}
+	return style + text + reset
+}
+
+// onDiagonal reports whether a cell lies on a diagonal of an X grid
+func (v *Visualizer) onDiagonal(i, j int) bool {
+	return v.grid.HasVariant(types.X) && (i == j || i+j == v.grid.Size-1)
+}
+
+// isShaded reports whether a cell lies in a window of a hyper grid
+func (v *Visualizer) isShaded(i, j int) bool {
+	return v.shaded != nil && v.shaded[i*v.grid.Size+j]
+}